@@ -17,10 +17,10 @@ func main() {
 		log.Printf("user %v tweets: %v\n", s.Tweet.User.ScreenName, s.Tweet.Text)
 	})
 
-	sf := map[string]string{
-		"track": "macet, jakarta",
+	sf := &twitterstream.FilterParams{
+		Track: []string{"macet", "jakarta"},
 	}
-	err := client.Public.Filter(sf)
+	err := client.Public.FilterWith(sf)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,214 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenURL represents default OAuth 2 token endpoint used by
+// ClientCredentials
+const DefaultTokenURL = "https://api.twitter.com/oauth2/token"
+
+// Authenticator adds credentials to stream requests.
+type Authenticator interface {
+	// Authenticate authenticates req. params describes req as
+	// signed by OAuth 1.0a.
+	Authenticate(req *http.Request, params *RequestParams) error
+}
+
+// Invalidator is implemented by Authenticators caching credentials.
+// Invalidate is called when a request was rejected with 401, before
+// it is retried once.
+type Invalidator interface {
+	Invalidate()
+}
+
+// OAuth1 authenticates requests in user context with OAuth 1.0a
+// HMAC-SHA1 signatures.
+type OAuth1 struct {
+	ConsumerKey    string
+	ConsumerSecret string
+	Token          string
+	TokenSecret    string
+
+	// NonceSource, if set, returns the oauth_nonce of each request
+	// instead of Nonce.
+	NonceSource func() string
+}
+
+// Authenticate sets the Authorization header of req.
+func (a *OAuth1) Authenticate(req *http.Request, params *RequestParams) error {
+	auth, err := a.header(params)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	return nil
+}
+
+// header returns the Authorization header of the request described
+// by rp, signed at rp.Time.
+func (a *OAuth1) header(rp *RequestParams) (string, error) {
+	ts := rp.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	nonce := Nonce(42)
+	if a.NonceSource != nil {
+		nonce = a.NonceSource()
+	}
+	op := map[string]string{
+		"oauth_nonce":            nonce,
+		"oauth_token":            a.Token,
+		"oauth_signature_method": "HMAC-SHA1",
+		"oauth_timestamp":        strconv.FormatInt(ts.Unix(), 10),
+		"oauth_consumer_key":     a.ConsumerKey,
+		"oauth_version":          "1.0",
+	}
+	rp.OAuth = op
+
+	// This will be sorted later
+	var keys []string
+
+	for k, v := range op {
+		ke := escape(k)
+		op[ke] = escape(v)
+		keys = append(keys, ke)
+	}
+
+	signature, err := Signature(a.ConsumerSecret, a.TokenSecret, SignatureBaseString(rp))
+	if err != nil {
+		return "", fmt.Errorf("twitterstream: error generating signature: %w", err)
+	}
+	op["oauth_signature"] = escape(signature)
+	keys = append(keys, "oauth_signature")
+
+	sort.Strings(keys)
+
+	authStr := "OAuth "
+	for _, k := range keys {
+		authStr += fmt.Sprintf("%s=\"%s\", ", k, op[k])
+	}
+	authStr = strings.Trim(authStr, ", ")
+	return authStr, nil
+}
+
+// BearerToken authenticates requests with a fixed application-only
+// bearer token.
+type BearerToken string
+
+// Authenticate sets the Authorization header of req.
+func (t BearerToken) Authenticate(req *http.Request, params *RequestParams) error {
+	req.Header.Set("Authorization", "Bearer "+string(t))
+	return nil
+}
+
+// ClientCredentials authenticates requests with an application-only
+// bearer token obtained with the client credentials grant. The token
+// is exchanged on first use and cached until invalidated.
+type ClientCredentials struct {
+	ConsumerKey    string
+	ConsumerSecret string
+
+	// Token endpoint, DefaultTokenURL if empty
+	TokenURL string
+
+	// HTTP client exchanging tokens, http.DefaultClient if nil
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// Authenticate sets the Authorization header of req, exchanging a
+// token first if none is cached.
+func (cc *ClientCredentials) Authenticate(req *http.Request, params *RequestParams) error {
+	token, err := cc.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Invalidate drops the cached token, so the next request exchanges
+// a new one.
+func (cc *ClientCredentials) Invalidate() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.token = ""
+}
+
+// Token returns the cached bearer token, exchanging one if needed.
+func (cc *ClientCredentials) Token(ctx context.Context) (string, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.token != "" {
+		return cc.token, nil
+	}
+	token, err := cc.exchange(ctx)
+	if err != nil {
+		return "", err
+	}
+	cc.token = token
+	return token, nil
+}
+
+// exchange requests a bearer token from the token endpoint.
+func (cc *ClientCredentials) exchange(ctx context.Context) (string, error) {
+	tokenURL := cc.TokenURL
+	if tokenURL == "" {
+		tokenURL = DefaultTokenURL
+	}
+	client := cc.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	body := strings.NewReader("grant_type=client_credentials")
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, body)
+	if err != nil {
+		return "", err
+	}
+	credentials := url.QueryEscape(cc.ConsumerKey) + ":" + url.QueryEscape(cc.ConsumerSecret)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=UTF-8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	if err := CheckResponse(resp); err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		TokenType   string `json:"token_type"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(token.TokenType, "bearer") || token.AccessToken == "" {
+		return "", errors.New("twitterstream: token endpoint returned no bearer token")
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestOAuth1 signs the example request of
+// https://dev.twitter.com/docs/auth/creating-signature. The example
+// of the authorizing-request page shares its parameters but was
+// signed with other secrets, so its signature cannot be reproduced.
+func TestOAuth1(t *testing.T) {
+	a := &OAuth1{
+		ConsumerKey:    "xvz1evFS4wEEPTGEFPHBog",
+		ConsumerSecret: "kAcSOqF21Fu85e7zjz7ZN2U4ZRhfV3WpwPAoE3Z7kBw",
+		Token:          "370773112-GmHxMAgYyLbNEtIKZeRNFsMKPR9EyMZeS9weJAEb",
+		TokenSecret:    "LswwdoUaIvS8ltyTt5jkRh4J50vUPVVHtR2YPi5kE",
+		NonceSource: func() string {
+			return "kYjzVBB8Y0ZFabxSWbWovY3uYSQ2pTgmZeNu2VS4cg"
+		},
+	}
+	rp := &RequestParams{
+		Method:   "POST",
+		Endpoint: "https://api.twitter.com/1.1/statuses/update.json",
+		Query:    map[string]string{"include_entities": "true"},
+		Body:     map[string]string{"status": escape("Hello Ladies + Gentlemen, a signed OAuth request!")},
+		Time:     time.Unix(1318622958, 0),
+	}
+	req, _ := http.NewRequest("POST", rp.Endpoint, nil)
+
+	if err := a.Authenticate(req, rp); err != nil {
+		t.Fatal(err)
+	}
+	want := `OAuth oauth_consumer_key="xvz1evFS4wEEPTGEFPHBog", ` +
+		`oauth_nonce="kYjzVBB8Y0ZFabxSWbWovY3uYSQ2pTgmZeNu2VS4cg", ` +
+		`oauth_signature="hCtSmYh%2BiHYCEqBWrE7C7hYmtUk%3D", ` +
+		`oauth_signature_method="HMAC-SHA1", ` +
+		`oauth_timestamp="1318622958", ` +
+		`oauth_token="370773112-GmHxMAgYyLbNEtIKZeRNFsMKPR9EyMZeS9weJAEb", ` +
+		`oauth_version="1.0"`
+	if actual := req.Header.Get("Authorization"); actual != want {
+		t.Errorf("Authorization header =\n%s\nwant\n%s", actual, want)
+	}
+}
+
+func TestNonceSource(t *testing.T) {
+	client := NewClient(&Config{
+		NonceSource: func() string {
+			return "fixed"
+		},
+	})
+	req, err := client.NewRequest("GET", "statuses/sample.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, `oauth_nonce="fixed"`) {
+		t.Errorf("Authorization header %q does not use the nonce source", auth)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	client := NewClient(&Config{Authenticator: BearerToken("AAAA%2FAAA")})
+	req, err := client.NewRequest("GET", "statuses/sample.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth, want := req.Header.Get("Authorization"), "Bearer AAAA%2FAAA"; auth != want {
+		t.Errorf("Authorization header = %q, want %q", auth, want)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	var exchanges atomic.Int32
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, secret, _ := r.BasicAuth()
+		r.ParseForm()
+		if key != "key" || secret != "secret" || r.Form.Get("grant_type") != "client_credentials" {
+			http.Error(w, `{"errors":[{"code":99,"message":"Unable to verify your credentials"}]}`, http.StatusForbidden)
+			return
+		}
+		n := exchanges.Add(1)
+		fmt.Fprintf(w, `{"token_type":"bearer","access_token":"token-%d"}`, n)
+	}))
+	defer tokens.Close()
+
+	// The stream only accepts the second token, as if the first one
+	// had been revoked.
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "{\"limit\":{\"track\":1}}\r\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	cc := &ClientCredentials{ConsumerKey: "key", ConsumerSecret: "secret", TokenURL: tokens.URL}
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/", Authenticator: cc})
+
+	conn, err := client.Public.OpenSample(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	waitMessages(t, conn, 1)
+	if n := exchanges.Load(); n != 2 {
+		t.Errorf("%d token exchanges, want 2", n)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d stream requests, want 2", n)
+	}
+
+	// The token is cached.
+	if token, err := cc.Token(context.Background()); token != "token-2" || err != nil {
+		t.Errorf("Token() = %q, %v, want token-2", token, err)
+	}
+	if n := exchanges.Load(); n != 2 {
+		t.Errorf("%d token exchanges after Token, want 2", n)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ClockOffset returns how far the clock of Twitter is ahead of the
+// local clock, as last measured from a 401 response. It is added to
+// the time of OAuth timestamps.
+func (c *Client) ClockOffset() time.Duration {
+	return time.Duration(c.clockOffset.Load())
+}
+
+// now returns the local time corrected by the clock offset.
+func (c *Client) now() time.Time {
+	return time.Now().Add(c.ClockOffset())
+}
+
+// adjustClock updates the clock offset from the Date header of the
+// 401 response carried by err, received on conn. It reports whether
+// the offset changed, in which case re-signing the request may
+// succeed.
+func (c *Client) adjustClock(conn *Connection, err error) bool {
+	var e *ErrorResponse
+	if !errors.As(err, &e) || e.Response.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	date, perr := http.ParseTime(e.Response.Header.Get("Date"))
+	if perr != nil {
+		return false
+	}
+
+	// Date has a resolution of one second, smaller differences
+	// cannot be the cause of the 401.
+	offset := time.Until(date).Round(time.Second)
+	if d := offset - c.ClockOffset(); -time.Second <= d && d <= time.Second {
+		return false
+	}
+	c.clockOffset.Store(int64(offset))
+	conn.logger.Warn("twitterstream: adjusted clock offset", "offset", offset)
+	return true
+}
@@ -0,0 +1,88 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var timestampPattern = regexp.MustCompile(`oauth_timestamp="(\d+)"`)
+
+// newSkewedServer returns a server whose clock is skew ahead of the
+// local one and that rejects requests signed more than five minutes
+// off its clock.
+func newSkewedServer(skew time.Duration, requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		now := time.Now().Add(skew)
+
+		m := timestampPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+		ts, _ := strconv.ParseInt(m[1], 10, 64)
+		if d := now.Sub(time.Unix(ts, 0)); d < -5*time.Minute || d > 5*time.Minute {
+			w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+			http.Error(w, `{"errors":[{"code":135,"message":"Timestamp out of bounds."}]}`, http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprint(w, "{\"limit\":{\"track\":1}}\r\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+}
+
+func TestClockSkewRetry(t *testing.T) {
+	var requests atomic.Int32
+	ts := newSkewedServer(time.Hour, &requests)
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/", MaxReconnects: -1})
+
+	conn, err := client.Public.OpenSample(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	waitMessages(t, conn, 1)
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+	if d := client.ClockOffset() - time.Hour; d < -2*time.Second || d > 2*time.Second {
+		t.Errorf("ClockOffset() = %v, want about %v", client.ClockOffset(), time.Hour)
+	}
+}
+
+func TestUnauthorizedWithoutSkew(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	err := client.Public.Sample()
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Sample returned %v, want ErrUnauthorized", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+	if off := client.ClockOffset(); off != 0 {
+		t.Errorf("ClockOffset() = %v, want 0", off)
+	}
+}
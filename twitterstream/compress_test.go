@@ -0,0 +1,87 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGzipStream(t *testing.T) {
+	// next tells the server to send the following message, which it
+	// only does once the previous one has been handled, so messages
+	// must be delivered as each gzip flush arrives.
+	next := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ae := r.Header.Get("Accept-Encoding"); !strings.Contains(ae, "gzip") {
+			t.Errorf("Accept-Encoding = %q, want gzip", ae)
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+
+		zw := gzip.NewWriter(w)
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(zw, "{\"limit\":{\"track\":%d}}\r\n", i)
+			zw.Flush()
+			w.(http.Flusher).Flush()
+
+			select {
+			case <-next:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		zw.Close()
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{
+		BaseURL:       ts.URL + "/1.1/",
+		MaxReconnects: -1,
+	})
+
+	tracks := make(chan int64)
+	client.HandleFunc("limit", func(s *Stream) {
+		tracks <- s.LimitNotice.Limit.Track
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Public.SampleContext(ctx)
+
+	for want := int64(1); want <= 3; want++ {
+		select {
+		case track := <-tracks:
+			if track != want {
+				t.Errorf("limit track = %d, want %d", track, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message %d was not delivered before the next gzip flush", want)
+		}
+		next <- struct{}{}
+	}
+}
+
+func TestDisableCompression(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ae := r.Header.Get("Accept-Encoding"); strings.Contains(ae, "gzip") || strings.Contains(ae, "deflate") {
+			t.Errorf("Accept-Encoding = %q with DisableCompression", ae)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{
+		BaseURL:            ts.URL + "/1.1/",
+		MaxReconnects:      -1,
+		DisableCompression: true,
+	})
+	client.Public.Sample()
+}
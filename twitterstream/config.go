@@ -6,11 +6,10 @@
 package twitterstream
 
 import (
-	"fmt"
-	"log"
-	"sort"
-	"strconv"
-	"strings"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -21,41 +20,87 @@ type Config struct {
 	OAuthTokenSecret string
 	UserAgent        string
 	BaseURL          string
-}
 
-func (conf *Config) authorizationHeader(rp *RequestParams) string {
-	op := map[string]string{
-		"oauth_nonce":            Nonce(42),
-		"oauth_token":            conf.OAuthToken,
-		"oauth_signature_method": "HMAC-SHA1",
-		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
-		"oauth_consumer_key":     conf.ConsumerKey,
-		"oauth_version":          "1.0",
-	}
-	rp.OAuth = op
+	// UserStreamBaseURL and SiteStreamBaseURL override the base URL
+	// of user and site streams, as BaseURL does for public streams.
+	UserStreamBaseURL string
+	SiteStreamBaseURL string
 
-	// This will be sorted later
-	var keys []string
+	// MaxReconnects is the number of consecutive reconnect attempts
+	// made before a stream gives up. Zero means the package default
+	// MaxReconnects, a negative value disables reconnecting.
+	MaxReconnects int
 
-	for k, v := range op {
-		ke := escape(k)
-		op[ke] = escape(v)
-		keys = append(keys, ke)
-	}
+	// OnReconnect, if set, is called before each reconnect attempt
+	// with the reconnecting connection, the attempt number, the wait
+	// before reconnecting and the error that ended the previous
+	// connection. Connections reconnect independently, so it may be
+	// called concurrently and must be safe for that.
+	OnReconnect func(conn *Connection, attempt int, wait time.Duration, err error)
 
-	signature, err := Signature(conf.ConsumerSecret, conf.OAuthTokenSecret, SignatureBaseString(rp))
-	if err != nil {
-		log.Printf("twitterstream: error generating signature %s\n", err)
-	}
-	op["oauth_signature"] = escape(signature)
-	keys = append(keys, "oauth_signature")
+	// StallTimeout is how long a stream may go without receiving any
+	// bytes before it is closed and reconnected. Zero means
+	// DefaultStallTimeout.
+	StallTimeout time.Duration
+
+	// NonceSource, if set, returns the oauth_nonce of each request
+	// instead of Nonce. Nonces must not repeat, this is meant for
+	// tests needing deterministic signatures.
+	NonceSource func() string
+
+	// Authenticator, if set, authenticates stream requests instead
+	// of OAuth1 with the credentials above.
+	Authenticator Authenticator
+
+	// Logger receives the log records of the client, with stream
+	// type, connection ID and reconnect attempt as attributes. The
+	// client is silent when it is nil.
+	Logger *slog.Logger
+
+	// Dispatch configures the workers that run stream handlers.
+	Dispatch DispatchConfig
 
-	sort.Strings(keys)
+	// OnHandlerError, if set, is called with the type and raw bytes
+	// of a message whose handler returned an error or panicked.
+	// Panics are reported as a *PanicError. It runs on the handler
+	// goroutine.
+	OnHandlerError func(streamType string, raw []byte, err error)
 
-	authStr := "OAuth "
-	for _, k := range keys {
-		authStr += fmt.Sprintf("%s=\"%s\", ", k, op[k])
+	// Framing selects how messages are delimited in streams.
+	// FramingLength adds delimited=length to every stream request.
+	Framing Framing
+
+	// DisableCompression stops the client from requesting gzip
+	// compressed streams. With HTTPClient or Transport set, their
+	// transport must have compression disabled as well, otherwise
+	// it requests gzip on its own.
+	DisableCompression bool
+
+	// HTTPClient, if set, sends all stream requests. Its Timeout
+	// should be zero since it would cut off the stream body.
+	HTTPClient *http.Client
+
+	// Transport, if set and HTTPClient is nil, is used instead of
+	// the transport returned by NewStreamTransport.
+	Transport http.RoundTripper
+
+	// Proxy and TLSConfig, if set, replace the proxy and TLS
+	// configuration of the default stream transport. They are
+	// ignored when HTTPClient or Transport is set.
+	Proxy     func(*http.Request) (*url.URL, error)
+	TLSConfig *tls.Config
+}
+
+// authenticator returns the Authenticator described by conf.
+func (conf *Config) authenticator() Authenticator {
+	if conf.Authenticator != nil {
+		return conf.Authenticator
+	}
+	return &OAuth1{
+		ConsumerKey:    conf.ConsumerKey,
+		ConsumerSecret: conf.ConsumerSecret,
+		Token:          conf.OAuthToken,
+		TokenSecret:    conf.OAuthTokenSecret,
+		NonceSource:    conf.NonceSource,
 	}
-	authStr = strings.Trim(authStr, ", ")
-	return authStr
 }
@@ -0,0 +1,288 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// Connection is a stream opened by one of the Open methods. It
+// reconnects on its own until it is closed, its context is done, the
+// client is disconnected or reconnecting gives up. Any number of
+// connections can run on one Client.
+type Connection struct {
+	id     uint64
+	logger *slog.Logger
+
+	// Handlers scoped to this connection, nil when it only uses the
+	// client's handlers
+	mux *ProcessStreamMux
+
+	// Set when messages only go to the channels
+	noHandlers bool
+
+	cancel context.CancelFunc
+	closed atomic.Bool
+	done   chan struct{}
+	err    error
+
+	// Channels requested with WithMessages, WithTweets and
+	// WithDeletes
+	messagesCh chan *Stream
+	tweetsCh   chan *Tweet
+	deletesCh  chan *DeletedStatus
+
+	messages    atomic.Int64
+	reconnects  atomic.Int64
+	dropped     atomic.Int64
+	connected   atomic.Int64
+	lastMessage atomic.Int64
+}
+
+// ConnStats represents counters of a Connection.
+type ConnStats struct {
+	// Messages received, across reconnects
+	Messages int64
+
+	// Reconnect attempts made
+	Reconnects int64
+
+	// Messages dropped by the dispatch overflow policy
+	Dropped int64
+
+	// When the current or last connection was established, zero
+	// if never connected
+	Connected time.Time
+
+	// When the last message was received, zero if none was
+	LastMessage time.Time
+}
+
+// ConnOption configures a Connection before it is opened.
+type ConnOption func(*Connection) error
+
+// WithHandler registers handler for streamType on the connection
+// only. Messages with a connection handler are not passed to the
+// handler registered on the client for the same type, other
+// messages still are.
+func WithHandler(streamType string, handler func(*Stream)) ConnOption {
+	return func(conn *Connection) error {
+		if !isValidStreamType(streamType) {
+			return fmt.Errorf("twitterstream: unknown stream type %v", streamType)
+		}
+		if conn.mux == nil {
+			conn.mux = newProcessStreamMux()
+		}
+		conn.mux.handle(streamType, HandlerFunc(handler))
+		return nil
+	}
+}
+
+// WithMessages makes every message of the connection available on
+// Messages, through a channel with the given buffer size.
+func WithMessages(buffer int) ConnOption {
+	return func(conn *Connection) error {
+		conn.messagesCh = make(chan *Stream, buffer)
+		return nil
+	}
+}
+
+// WithTweets makes the tweets of the connection available on
+// Tweets, through a channel with the given buffer size.
+func WithTweets(buffer int) ConnOption {
+	return func(conn *Connection) error {
+		conn.tweetsCh = make(chan *Tweet, buffer)
+		return nil
+	}
+}
+
+// WithDeletes makes the tweet deletion notices of the connection
+// available on Deletes, through a channel with the given buffer
+// size.
+func WithDeletes(buffer int) ConnOption {
+	return func(conn *Connection) error {
+		conn.deletesCh = make(chan *DeletedStatus, buffer)
+		return nil
+	}
+}
+
+// withoutHandlers keeps messages of the connection from being
+// passed to any handler.
+func withoutHandlers() ConnOption {
+	return func(conn *Connection) error {
+		conn.noHandlers = true
+		return nil
+	}
+}
+
+// ID returns the identifier of the connection, unique within its
+// Client.
+func (conn *Connection) ID() uint64 {
+	return conn.id
+}
+
+// Done returns a channel that is closed once the connection has
+// ended and its reader has stopped.
+func (conn *Connection) Done() <-chan struct{} {
+	return conn.done
+}
+
+// Err returns the error that ended the connection. It returns nil
+// while the connection runs, and after it was ended by Close or
+// Client.Disconnect.
+func (conn *Connection) Err() error {
+	select {
+	case <-conn.done:
+		return conn.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the connection and waits for its reader to stop.
+// Messages already queued are still handled. It may be called from
+// a handler of the connection.
+func (conn *Connection) Close() error {
+	conn.closed.Store(true)
+	conn.cancel()
+	<-conn.done
+	return nil
+}
+
+// Messages returns the channel of messages requested with
+// WithMessages, or nil. Messages are sent in stream order by the
+// reader of the connection, which waits while the channel is full,
+// so a slow receiver holds up the stream. The channel is closed
+// once the connection has ended, after which Err reports why.
+//
+// Messages are passed to the handlers as well.
+func (conn *Connection) Messages() <-chan *Stream {
+	return conn.messagesCh
+}
+
+// Tweets is like Messages for the channel requested with
+// WithTweets.
+func (conn *Connection) Tweets() <-chan *Tweet {
+	return conn.tweetsCh
+}
+
+// Deletes is like Messages for the channel requested with
+// WithDeletes.
+func (conn *Connection) Deletes() <-chan *DeletedStatus {
+	return conn.deletesCh
+}
+
+// deliver sends s on the requested channels. It gives up when ctx
+// is done.
+func (conn *Connection) deliver(ctx context.Context, s *Stream) {
+	if conn.messagesCh != nil {
+		select {
+		case conn.messagesCh <- s:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if conn.tweetsCh != nil && s.Tweet != nil {
+		select {
+		case conn.tweetsCh <- s.Tweet:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if conn.deletesCh != nil && s.TweetDeletionNotice != nil {
+		select {
+		case conn.deletesCh <- s.TweetDeletionNotice.Delete.Status:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// closeChannels closes the requested channels. It is called once
+// the reader has stopped.
+func (conn *Connection) closeChannels() {
+	if conn.messagesCh != nil {
+		close(conn.messagesCh)
+	}
+	if conn.tweetsCh != nil {
+		close(conn.tweetsCh)
+	}
+	if conn.deletesCh != nil {
+		close(conn.deletesCh)
+	}
+}
+
+// Stats returns the current counters of the connection.
+func (conn *Connection) Stats() ConnStats {
+	return ConnStats{
+		Messages:    conn.messages.Load(),
+		Reconnects:  conn.reconnects.Load(),
+		Dropped:     conn.dropped.Load(),
+		Connected:   unixNano(conn.connected.Load()),
+		LastMessage: unixNano(conn.lastMessage.Load()),
+	}
+}
+
+func unixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// newConnection returns a connection that is not running yet.
+func (c *Client) newConnection(opts []ConnOption) (*Connection, error) {
+	id := c.nextConnID.Add(1)
+	conn := &Connection{
+		id:     id,
+		logger: c.logger.With("conn_id", id),
+		cancel: func() {},
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(conn); err != nil {
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// open starts a connection to urlStr, resolved against baseURL.
+func (c *Client) open(ctx context.Context, baseURL *url.URL, method, urlStr string, body map[string]string, opts []ConnOption) (*Connection, error) {
+	conn, err := c.newConnection(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, conn.cancel = context.WithCancel(ctx)
+	go func() {
+		err := c.connect(ctx, conn, baseURL, method, urlStr, body)
+		if conn.closed.Load() {
+			err = nil
+		}
+		conn.err = err
+		conn.cancel()
+
+		// Done is closed first so that Err is set for receivers
+		// woken by the channels closing.
+		close(conn.done)
+		conn.closeChannels()
+	}()
+	return conn, nil
+}
+
+// waitConn blocks until conn has ended and returns its error.
+func waitConn(conn *Connection, err error) error {
+	if err != nil {
+		return err
+	}
+	<-conn.Done()
+	return conn.Err()
+}
@@ -0,0 +1,255 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitMessages waits until conn has received at least n messages.
+func waitMessages(t *testing.T, conn *Connection, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for conn.Stats().Messages < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d messages received, Err() = %v", conn.Stats().Messages, n, conn.Err())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConnections(t *testing.T) {
+	ts := newLimitServer(5)
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	var shared, scoped atomic.Int32
+	client.HandleFunc("limit", func(s *Stream) {
+		shared.Add(1)
+	})
+
+	ctx := context.Background()
+	sample, err := client.Public.OpenSample(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := client.Public.OpenFilterWith(ctx, &FilterParams{Track: []string{"go"}},
+		WithHandler("limit", func(s *Stream) {
+			scoped.Add(1)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.ID() == filter.ID() {
+		t.Errorf("connections share ID %v", sample.ID())
+	}
+
+	waitMessages(t, sample, 5)
+	waitMessages(t, filter, 5)
+
+	if err := sample.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+	select {
+	case <-sample.Done():
+	default:
+		t.Error("Done not closed after Close")
+	}
+	if err := sample.Err(); err != nil {
+		t.Errorf("Err after Close = %v, want nil", err)
+	}
+
+	// The filter connection keeps running on its own.
+	if err := filter.Err(); err != nil {
+		t.Errorf("Err of running connection = %v, want nil", err)
+	}
+	filter.Close()
+
+	st := filter.Stats()
+	if st.Connected.IsZero() || st.LastMessage.Before(st.Connected) {
+		t.Errorf("Stats() times = %v, %v", st.Connected, st.LastMessage)
+	}
+
+	client.Shutdown(ctx)
+	if n := shared.Load(); n != 5 {
+		t.Errorf("shared handler called %d times, want 5", n)
+	}
+	if n := scoped.Load(); n != 5 {
+		t.Errorf("scoped handler called %d times, want 5", n)
+	}
+}
+
+func TestConnectionErr(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	conn, err := client.Public.OpenSample(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-conn.Done()
+	if _, ok := conn.Err().(*ErrorResponse); !ok {
+		t.Errorf("Err() = %v, want *ErrorResponse", conn.Err())
+	}
+	if n := conn.Stats().Reconnects; n != 0 {
+		t.Errorf("Stats().Reconnects = %d, want 0", n)
+	}
+}
+
+func TestWithHandlerUnknownType(t *testing.T) {
+	client := NewClient(&Config{})
+	_, err := client.Public.OpenSample(context.Background(), WithHandler("nope", func(*Stream) {}))
+	if err == nil {
+		t.Error("OpenSample with unknown handler type returned no error")
+	}
+}
+
+func TestConnectionChannels(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"text":"hello","user":{"screen_name":"gopher"}}`+"\r\n")
+		fmt.Fprint(w, `{"delete":{"status":{"id":1,"user_id":2}}}`+"\r\n")
+		fmt.Fprint(w, `{"limit":{"track":3}}`+"\r\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, err := client.Public.OpenSample(ctx, WithMessages(0), WithTweets(0), WithDeletes(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	var tweets, deletes int
+	messages, tw, del := conn.Messages(), conn.Tweets(), conn.Deletes()
+	timeout := time.After(2 * time.Second)
+	for messages != nil || tw != nil || del != nil {
+		select {
+		case s, ok := <-messages:
+			if !ok {
+				messages = nil
+				continue
+			}
+			if types = append(types, s.Type); len(types) == 3 {
+				cancel()
+			}
+		case tweet, ok := <-tw:
+			if !ok {
+				tw = nil
+				continue
+			}
+			if tweet.Text != "hello" {
+				t.Errorf("tweet text = %q, want %q", tweet.Text, "hello")
+			}
+			tweets++
+		case d, ok := <-del:
+			if !ok {
+				del = nil
+				continue
+			}
+			if d.ID != 1 {
+				t.Errorf("deleted status ID = %d, want 1", d.ID)
+			}
+			deletes++
+		case <-timeout:
+			t.Fatal("channels not closed")
+		}
+	}
+
+	if want := []string{"tweet", "delete", "limit"}; fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("message types = %v, want %v", types, want)
+	}
+	if tweets != 1 || deletes != 1 {
+		t.Errorf("received %d tweets and %d deletes, want 1 and 1", tweets, deletes)
+	}
+	if err := conn.Err(); err != context.Canceled {
+		t.Errorf("Err() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestConnectionLogger(t *testing.T) {
+	ts := newLimitServer(1)
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	client := NewClient(&Config{
+		BaseURL: ts.URL + "/1.1/",
+		Logger:  slog.New(slog.NewTextHandler(&buf, nil)),
+	})
+	client.HandleErrorFunc("limit", func(s *Stream) error {
+		return errors.New("bad limit")
+	})
+
+	conn, err := client.Public.OpenSample(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitMessages(t, conn, 1)
+	conn.Close()
+	client.Shutdown(context.Background())
+
+	out := buf.String()
+	for _, want := range []string{
+		"msg=\"twitterstream: connected\"",
+		"level=ERROR msg=\"twitterstream: stream handler failed\" conn_id=1 stream_type=limit error=\"bad limit\"",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output does not contain %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestConnectionCloseFromHandler(t *testing.T) {
+	ts := newLimitServer(20)
+	defer ts.Close()
+
+	client := NewClient(&Config{
+		BaseURL:  ts.URL + "/1.1/",
+		Dispatch: DispatchConfig{QueueSize: 2},
+	})
+
+	opened := make(chan *Connection, 1)
+	closed := make(chan struct{})
+	var once atomic.Bool
+	conn, err := client.Public.OpenSample(context.Background(), WithHandler("limit", func(s *Stream) {
+		if once.CompareAndSwap(false, true) {
+			(<-opened).Close()
+			close(closed)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened <- conn
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close called from a handler did not return")
+	}
+	if err := conn.Err(); err != nil {
+		t.Errorf("Err() = %v after Close, want nil", err)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// errSyntax is returned by scanKeys for malformed messages.
+var errSyntax = errors.New("twitterstream: malformed message")
+
+// streamKeys is a set of the top-level keys that identify a
+// message type.
+type streamKeys uint32
+
+const (
+	keyControl streamKeys = 1 << iota
+	keyDisconnect
+	keyWarning
+	keyDelete
+	keyScrubGeo
+	keyLimit
+	keyDirectMessage
+	keyStatusWithheld
+	keyUserWithheld
+	keyEvent
+	keyFriends
+	keyText
+	keyUser
+	keyForUser
+)
+
+// streamKey returns the streamKeys bit for key, or zero if key
+// does not identify a message type.
+func streamKey(key []byte) streamKeys {
+	switch string(key) {
+	case "control":
+		return keyControl
+	case "disconnect":
+		return keyDisconnect
+	case "warning":
+		return keyWarning
+	case "delete":
+		return keyDelete
+	case "scrub_geo":
+		return keyScrubGeo
+	case "limit":
+		return keyLimit
+	case "direct_message":
+		return keyDirectMessage
+	case "status_withheld":
+		return keyStatusWithheld
+	case "user_withheld":
+		return keyUserWithheld
+	case "event":
+		return keyEvent
+	case "friends":
+		return keyFriends
+	case "text":
+		return keyText
+	case "user":
+		return keyUser
+	case "for_user":
+		return keyForUser
+	}
+	return 0
+}
+
+// scanKeys returns the set of known keys found at the top level of
+// the JSON object in raw. Values are skipped without being decoded.
+func scanKeys(raw []byte) (streamKeys, error) {
+	var keys streamKeys
+
+	i := skipSpace(raw, 0)
+	if i >= len(raw) || raw[i] != '{' {
+		return 0, errSyntax
+	}
+	i = skipSpace(raw, i+1)
+	if i < len(raw) && raw[i] == '}' {
+		return keys, checkEnd(raw, i+1)
+	}
+
+	for {
+		if i >= len(raw) || raw[i] != '"' {
+			return 0, errSyntax
+		}
+		end := skipString(raw, i)
+		if end < 0 {
+			return 0, errSyntax
+		}
+		keys |= streamKey(raw[i+1 : end-1])
+
+		i = skipSpace(raw, end)
+		if i >= len(raw) || raw[i] != ':' {
+			return 0, errSyntax
+		}
+		if i = skipValue(raw, skipSpace(raw, i+1)); i < 0 {
+			return 0, errSyntax
+		}
+
+		i = skipSpace(raw, i)
+		if i >= len(raw) {
+			return 0, errSyntax
+		}
+		switch raw[i] {
+		case ',':
+			i = skipSpace(raw, i+1)
+		case '}':
+			return keys, checkEnd(raw, i+1)
+		default:
+			return 0, errSyntax
+		}
+	}
+}
+
+// checkEnd returns errSyntax if anything but whitespace follows
+// raw[i].
+func checkEnd(raw []byte, i int) error {
+	if skipSpace(raw, i) != len(raw) {
+		return errSyntax
+	}
+	return nil
+}
+
+// skipSpace returns the index of the first non-whitespace byte in
+// raw at or after i.
+func skipSpace(raw []byte, i int) int {
+	for i < len(raw) {
+		switch raw[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// skipString returns the index just past the string starting at
+// raw[i], or -1 if the string is not terminated.
+func skipString(raw []byte, i int) int {
+	for i++; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// skipValue returns the index just past the value starting at
+// raw[i], or -1 if the value is not terminated.
+func skipValue(raw []byte, i int) int {
+	if i >= len(raw) {
+		return -1
+	}
+
+	switch raw[i] {
+	case '"':
+		return skipString(raw, i)
+	case '{', '[':
+		depth := 0
+		for i < len(raw) {
+			switch raw[i] {
+			case '"':
+				if i = skipString(raw, i); i < 0 {
+					return -1
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+			i++
+		}
+		return -1
+	}
+
+	// Numbers, true, false and null run up to the next delimiter.
+	start := i
+	for i < len(raw) {
+		switch raw[i] {
+		case ',', '}', ']', ' ', '\t', '\r', '\n':
+			if i == start {
+				return -1
+			}
+			return i
+		}
+		i++
+	}
+	return -1
+}
+
+// decodeStream classifies raw by its top-level keys and decodes it
+// once into the matching type. Messages of unknown type are returned
+// with an empty Type.
+func decodeStream(raw []byte) (*Stream, error) {
+	keys, err := scanKeys(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &Stream{Raw: raw}
+
+	var container interface{}
+	switch {
+	case keys&keyControl != 0:
+		stream.Type = "control"
+		stream.ControlNotice = new(ControlNotice)
+		container = stream.ControlNotice
+	case keys&keyDisconnect != 0:
+		stream.Type = "disconnect"
+		stream.DisconnectNotice = new(DisconnectNotice)
+		container = stream.DisconnectNotice
+	case keys&keyWarning != 0:
+		stream.Type = "warning"
+		stream.WarningNotice = new(WarningNotice)
+		container = stream.WarningNotice
+	case keys&keyDelete != 0:
+		stream.Type = "delete"
+		stream.TweetDeletionNotice = new(TweetDeletionNotice)
+		container = stream.TweetDeletionNotice
+	case keys&keyScrubGeo != 0:
+		stream.Type = "scrub_geo"
+		stream.LocationDeletionNotice = new(LocationDeletionNotice)
+		container = stream.LocationDeletionNotice
+	case keys&keyLimit != 0:
+		stream.Type = "limit"
+		stream.LimitNotice = new(LimitNotice)
+		container = stream.LimitNotice
+	case keys&keyDirectMessage != 0:
+		stream.Type = "direct_message"
+		stream.DirectMessageNotice = new(DirectMessageNotice)
+		container = stream.DirectMessageNotice
+	case keys&keyStatusWithheld != 0:
+		stream.Type = "status_withheld"
+		stream.StatusWithheldNotice = new(StatusWithheldNotice)
+		container = stream.StatusWithheldNotice
+	case keys&keyUserWithheld != 0:
+		stream.Type = "user_withheld"
+		stream.UserWithheldNotice = new(UserWithheldNotice)
+		container = stream.UserWithheldNotice
+	case keys&keyEvent != 0:
+		stream.Type = "event"
+		stream.Event = new(Event)
+		container = stream.Event
+	case keys&keyFriends != 0:
+		stream.Type = "friends"
+		stream.FriendsLists = new(FriendsLists)
+		container = stream.FriendsLists
+	case keys&keyText != 0:
+		if keys&keyUser != 0 {
+			stream.Type = "tweet"
+			stream.Tweet = new(Tweet)
+			container = stream.Tweet
+		}
+	case keys&keyForUser != 0:
+		stream.Type = "for_user"
+		stream.ForUser = new(ForUser)
+		container = stream.ForUser
+	}
+
+	if container == nil {
+		return stream, nil
+	}
+	if err := json.Unmarshal(raw, container); err != nil {
+		return nil, err
+	}
+
+	// Deletion notices only count when they carry the status they
+	// refer to, other deletions (direct messages) are left unknown.
+	// Warnings about following too many users have their own type.
+	switch stream.Type {
+	case "warning":
+		if w := stream.WarningNotice.Warning; w != nil && w.Code == WarningFollowsOverLimit {
+			stream.Type = "too_many_follows"
+			stream.TooManyFollow = &TooManyFollow{
+				Code:    w.Code,
+				Message: w.Message,
+				UserID:  w.UserID,
+			}
+		}
+	case "delete":
+		if d := stream.TweetDeletionNotice.Delete; d == nil || d.Status == nil {
+			stream.Type, stream.TweetDeletionNotice = "", nil
+		}
+	case "scrub_geo":
+		if sg := stream.LocationDeletionNotice.ScrubGeo; sg == nil || sg.UpToStatusID == 0 {
+			stream.Type, stream.LocationDeletionNotice = "", nil
+		}
+	}
+	return stream, nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// sampleFixtures returns the messages in testdata/sample.json. They
+// are synthetic, not a recorded capture: tweets, deletes and other
+// notices with the fields and nesting of statuses/sample.json
+// output, but generated text and ids. Benchmark numbers on them
+// approximate real traffic only as far as that shape does.
+func sampleFixtures(tb testing.TB) [][]byte {
+	data, err := os.ReadFile("testdata/sample.json")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// legacyDecodeStream decodes raw the way streamSwitcher did before
+// decodeStream: into a map to find the type, then again into the
+// concrete type. It is kept to check and benchmark decodeStream
+// against.
+func legacyDecodeStream(raw []byte) (*Stream, error) {
+	var v map[string]interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+
+	stream := &Stream{Raw: raw}
+
+	var container interface{}
+	if _, ok := v["control"]; ok {
+	} else if _, ok := v["warning"]; ok {
+		stream.Type = "warning"
+		container = new(WarningNotice)
+		stream.WarningNotice = container.(*WarningNotice)
+	} else if d, ok := v["delete"]; ok {
+		ds := d.(map[string]interface{})
+		if _, ok = ds["status"]; ok {
+			stream.Type = "delete"
+			container = new(TweetDeletionNotice)
+			stream.TweetDeletionNotice = container.(*TweetDeletionNotice)
+		}
+	} else if sg, ok := v["scrub_geo"]; ok {
+		sgc := sg.(map[string]interface{})
+		if _, ok = sgc["up_to_status_id"]; ok {
+			stream.Type = "scrub_geo"
+			container = new(LocationDeletionNotice)
+			stream.LocationDeletionNotice = container.(*LocationDeletionNotice)
+		}
+	} else if _, ok := v["limit"]; ok {
+		stream.Type = "limit"
+		container = new(LimitNotice)
+		stream.LimitNotice = container.(*LimitNotice)
+	} else if _, ok := v["direct_message"]; ok {
+		stream.Type = "direct_message"
+		container = new(DirectMessageNotice)
+		stream.DirectMessageNotice = container.(*DirectMessageNotice)
+	} else if _, ok := v["status_withheld"]; ok {
+		stream.Type = "status_withheld"
+		container = new(StatusWithheldNotice)
+		stream.StatusWithheldNotice = container.(*StatusWithheldNotice)
+	} else if _, ok := v["user_withheld"]; ok {
+		stream.Type = "user_withheld"
+		container = new(UserWithheldNotice)
+		stream.UserWithheldNotice = container.(*UserWithheldNotice)
+	} else if _, ok := v["event"]; ok {
+		stream.Type = "event"
+		container = new(Event)
+		stream.Event = container.(*Event)
+	} else if _, ok := v["friends"]; ok {
+		stream.Type = "friends"
+		container = new(FriendsLists)
+		stream.FriendsLists = container.(*FriendsLists)
+	} else if _, ok := v["text"]; ok {
+		if _, ok = v["user"]; ok {
+			stream.Type = "tweet"
+			container = new(Tweet)
+			stream.Tweet = container.(*Tweet)
+		}
+	} else if _, ok := v["for_user"]; ok {
+		stream.Type = "for_user"
+		container = new(ForUser)
+		stream.ForUser = container.(*ForUser)
+	}
+
+	if container != nil {
+		if err := json.Unmarshal(raw, container); err != nil {
+			return nil, err
+		}
+	}
+	return stream, nil
+}
+
+func TestDecodeStreamMatchesLegacy(t *testing.T) {
+	lines := sampleFixtures(t)
+	lines = append(lines,
+		[]byte(`{"friends":[1497,169686021,790205,15211564]}`),
+		[]byte(`{"user_withheld":{"id":123456,"withheld_in_countries":["DE","AR"]}}`),
+		[]byte(`{"delete":{"direct_message":{"id":1234,"user_id":3}}}`),
+		[]byte(`{"text":"no user"}`),
+		[]byte(`{"unknown":{"nested":{"text":"x","user":{}}}}`),
+		[]byte(` { "limit" : { "track" : 1 } } `),
+	)
+
+	for _, raw := range lines {
+		actual, err := decodeStream(raw)
+		if err != nil {
+			t.Errorf("decodeStream(%s) returned error %v", raw, err)
+			continue
+		}
+		want, _ := legacyDecodeStream(raw)
+		if !reflect.DeepEqual(actual, want) {
+			t.Errorf("decodeStream(%s) = %+v, want %+v", raw, actual, want)
+		}
+	}
+}
+
+type DecodeStreamTest struct {
+	in   string
+	want *Stream
+}
+
+var decodeStreamTests = []DecodeStreamTest{
+	{
+		`{"control":{"control_uri":"/1.1/site/c/1_1_54e345d655ee3e8df359ac033648530bfbe26c5f"}}`,
+		&Stream{
+			Type: "control",
+			ControlNotice: &ControlNotice{
+				Control: &Control{ControlURI: "/1.1/site/c/1_1_54e345d655ee3e8df359ac033648530bfbe26c5f"},
+			},
+		},
+	},
+	{
+		`{"disconnect":{"code":4,"stream_name":"< A stream identifier >","reason":"< Human readable status message >"}}`,
+		&Stream{
+			Type: "disconnect",
+			DisconnectNotice: &DisconnectNotice{
+				Disconnect: &Disconnect{
+					Code:       4,
+					StreamName: "< A stream identifier >",
+					Reason:     "< Human readable status message >",
+				},
+			},
+		},
+	},
+	{
+		`{"warning":{"code":"FOLLOWS_OVER_LIMIT","message":"You are following more than 10000 users.","user_id":13}}`,
+		&Stream{
+			Type: "too_many_follows",
+			WarningNotice: &WarningNotice{
+				Warning: &Warning{
+					Code:    "FOLLOWS_OVER_LIMIT",
+					Message: "You are following more than 10000 users.",
+					UserID:  13,
+				},
+			},
+			TooManyFollow: &TooManyFollow{
+				Code:    "FOLLOWS_OVER_LIMIT",
+				Message: "You are following more than 10000 users.",
+				UserID:  13,
+			},
+		},
+	},
+}
+
+func TestDecodeStream(t *testing.T) {
+	for _, tt := range decodeStreamTests {
+		tt.want.Raw = []byte(tt.in)
+		actual, err := decodeStream([]byte(tt.in))
+		if err != nil {
+			t.Errorf("decodeStream(%s) returned error %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.want) {
+			t.Errorf("decodeStream(%s) = %+v, want %+v", tt.in, actual, tt.want)
+		}
+	}
+}
+
+var malformedStreams = []string{
+	``,
+	`[]`,
+	`"text"`,
+	`{`,
+	`{"text"}`,
+	`{"text":}`,
+	`{"text":"unterminated}`,
+	`{"limit":{"track":1}`,
+	`{"limit":{"track":1}}}`,
+	`{"a":1 "b":2}`,
+}
+
+func TestScanKeysMalformed(t *testing.T) {
+	for _, raw := range malformedStreams {
+		if _, err := scanKeys([]byte(raw)); err == nil {
+			t.Errorf("scanKeys(%q) returned no error", raw)
+		}
+	}
+}
+
+func benchmarkDecode(b *testing.B, decode func([]byte) (*Stream, error)) {
+	lines := sampleFixtures(b)
+
+	var size int64
+	for _, line := range lines {
+		size += int64(len(line))
+	}
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			if _, err := decode(line); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecodeStream(b *testing.B) {
+	benchmarkDecode(b, decodeStream)
+}
+
+func BenchmarkDecodeStreamLegacy(b *testing.B) {
+	benchmarkDecode(b, legacyDecodeStream)
+}
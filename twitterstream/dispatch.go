@@ -0,0 +1,209 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultQueueSize represents default capacity of each dispatch
+// worker queue.
+const DefaultQueueSize = 1000
+
+// OverflowPolicy tells the dispatcher what to do with a message
+// when the queue it belongs to is full.
+type OverflowPolicy int
+
+const (
+	// Block waits for room in the queue, which stops reading from
+	// the stream until handlers catch up.
+	Block OverflowPolicy = iota
+
+	// DropOldest discards the oldest queued message to make room.
+	DropOldest
+
+	// DropNewest discards the incoming message.
+	DropNewest
+)
+
+// DispatchConfig configures how decoded messages are handed to
+// handlers.
+type DispatchConfig struct {
+	// Workers is the number of goroutines running handlers.
+	// Defaults to 1.
+	Workers int
+
+	// QueueSize is the capacity of each worker queue. Defaults
+	// to DefaultQueueSize.
+	QueueSize int
+
+	// Key maps a message to its ordering key. Messages with the
+	// same key are always handled by the same worker, in the order
+	// they were read from the stream. If Key is nil every message
+	// shares one key, so messages are handled in stream order by a
+	// single worker. See KeyByUserID.
+	Key func(*Stream) string
+
+	// Overflow is applied when a worker queue is full.
+	Overflow OverflowPolicy
+
+	// OnDrop, if set, is called with every message discarded
+	// because of Overflow.
+	OnDrop func(*Stream)
+}
+
+// KeyByUserID is a DispatchConfig.Key that orders messages per
+// user ID, so that a user's deletes are never handled before the
+// tweets they refer to.
+func KeyByUserID(s *Stream) string {
+	var id int64
+	switch {
+	case s.Tweet != nil && s.Tweet.User != nil:
+		id = s.Tweet.User.ID
+	case s.TweetDeletionNotice != nil && s.TweetDeletionNotice.Delete != nil && s.TweetDeletionNotice.Delete.Status != nil:
+		id = s.TweetDeletionNotice.Delete.Status.UserID
+	case s.LocationDeletionNotice != nil && s.LocationDeletionNotice.ScrubGeo != nil:
+		id = s.LocationDeletionNotice.ScrubGeo.UserID
+	case s.StatusWithheldNotice != nil && s.StatusWithheldNotice.StatusWithheld != nil:
+		id = s.StatusWithheldNotice.StatusWithheld.UserID
+	case s.UserWithheldNotice != nil && s.UserWithheldNotice.UserWithheld != nil:
+		id = s.UserWithheldNotice.UserWithheld.ID
+	case s.DirectMessageNotice != nil && s.DirectMessageNotice.DirectMessage != nil:
+		id = s.DirectMessageNotice.DirectMessage.SenderID
+	case s.Event != nil && s.Event.Source != nil:
+		id = s.Event.Source.ID
+	default:
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// dispatcher hands messages to a fixed set of workers through
+// bounded queues.
+type dispatcher struct {
+	conf   DispatchConfig
+	handle func(*Stream)
+
+	queues  []chan *Stream
+	workers sync.WaitGroup
+	start   sync.Once
+	stop    sync.Once
+
+	// Set when remaining queued messages should be discarded
+	discard atomic.Bool
+
+	// Messages queued or being handled
+	pending atomic.Int64
+}
+
+func newDispatcher(conf DispatchConfig, handle func(*Stream)) *dispatcher {
+	if conf.Workers <= 0 || conf.Key == nil {
+		conf.Workers = 1
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = DefaultQueueSize
+	}
+	return &dispatcher{conf: conf, handle: handle}
+}
+
+// run starts the workers.
+func (d *dispatcher) run() {
+	d.queues = make([]chan *Stream, d.conf.Workers)
+	for i := range d.queues {
+		q := make(chan *Stream, d.conf.QueueSize)
+		d.queues[i] = q
+
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			for s := range q {
+				if !d.discard.Load() {
+					d.handle(s)
+				}
+				d.pending.Add(-1)
+			}
+		}()
+	}
+}
+
+// queue returns the worker queue for s.
+func (d *dispatcher) queue(s *Stream) chan *Stream {
+	if len(d.queues) == 1 {
+		return d.queues[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(d.conf.Key(s)))
+	return d.queues[h.Sum32()%uint32(len(d.queues))]
+}
+
+// dispatch queues s according to the overflow policy. With Block
+// it gives up waiting for room when ctx is done. It must not be
+// called after close.
+func (d *dispatcher) dispatch(ctx context.Context, s *Stream) {
+	d.start.Do(d.run)
+
+	q := d.queue(s)
+	d.pending.Add(1)
+
+	switch d.conf.Overflow {
+	case DropNewest:
+		select {
+		case q <- s:
+		default:
+			d.drop(s)
+		}
+	case DropOldest:
+		for {
+			select {
+			case q <- s:
+				return
+			default:
+			}
+			select {
+			case old := <-q:
+				d.drop(old)
+			default:
+			}
+		}
+	default:
+		select {
+		case q <- s:
+		case <-ctx.Done():
+			d.pending.Add(-1)
+		}
+	}
+}
+
+// drop accounts for a message discarded because of overflow.
+func (d *dispatcher) drop(s *Stream) {
+	d.pending.Add(-1)
+	if s.conn != nil {
+		s.conn.dropped.Add(1)
+	}
+	if d.conf.OnDrop != nil {
+		d.conf.OnDrop(s)
+	}
+}
+
+// close stops accepting messages and lets the workers exit once
+// their queues are drained.
+func (d *dispatcher) close() {
+	d.start.Do(d.run)
+	d.stop.Do(func() {
+		for _, q := range d.queues {
+			close(q)
+		}
+	})
+}
+
+// wait blocks until all workers have exited.
+func (d *dispatcher) wait() {
+	d.workers.Wait()
+}
@@ -0,0 +1,165 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func tweetFrom(userID, tweetID int64) *Stream {
+	return &Stream{
+		Type:  "tweet",
+		Tweet: &Tweet{ID: tweetID, User: &User{ID: userID}},
+	}
+}
+
+func TestDispatcherOrderPerKey(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[int64][]int64)
+
+	d := newDispatcher(DispatchConfig{Workers: 4, Key: KeyByUserID}, func(s *Stream) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[s.Tweet.User.ID] = append(seen[s.Tweet.User.ID], s.Tweet.ID)
+	})
+
+	for i := int64(0); i < 1000; i++ {
+		d.dispatch(context.Background(), tweetFrom(i%10, i))
+	}
+	d.close()
+	d.wait()
+
+	for user, ids := range seen {
+		if len(ids) != 100 {
+			t.Errorf("user %d: handled %d tweets, want 100", user, len(ids))
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] < ids[i-1] {
+				t.Errorf("user %d: tweet %d handled after %d", user, ids[i], ids[i-1])
+				break
+			}
+		}
+	}
+}
+
+func TestDispatcherOverflow(t *testing.T) {
+	for _, policy := range []OverflowPolicy{DropNewest, DropOldest} {
+		var handled, dropped []int64
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		d := newDispatcher(DispatchConfig{
+			QueueSize: 2,
+			Overflow:  policy,
+			OnDrop: func(s *Stream) {
+				dropped = append(dropped, s.Tweet.ID)
+			},
+		}, func(s *Stream) {
+			if s.Tweet.ID == 0 {
+				close(started)
+				<-release
+			}
+			handled = append(handled, s.Tweet.ID)
+		})
+
+		// Tweet 0 blocks the worker, 1 and 2 fill the queue.
+		d.dispatch(context.Background(), tweetFrom(1, 0))
+		<-started
+		for i := int64(1); i <= 4; i++ {
+			d.dispatch(context.Background(), tweetFrom(1, i))
+		}
+		close(release)
+		d.close()
+		d.wait()
+
+		want := map[OverflowPolicy]string{
+			DropNewest: "[0 1 2] [3 4]",
+			DropOldest: "[0 3 4] [1 2]",
+		}[policy]
+		if actual := fmt.Sprint(handled, dropped); actual != want {
+			t.Errorf("policy %d: handled, dropped = %s, want %s", policy, actual, want)
+		}
+	}
+}
+
+func TestDispatchBlockCancel(t *testing.T) {
+	ts := newLimitServer(20)
+	defer ts.Close()
+
+	client := NewClient(&Config{
+		BaseURL:  ts.URL + "/1.1/",
+		Dispatch: DispatchConfig{QueueSize: 2},
+	})
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	client.HandleFunc("limit", func(s *Stream) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- client.Public.SampleContext(ctx)
+	}()
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("SampleContext returned %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SampleContext still blocked on a full queue after cancel")
+	}
+}
+
+func TestDispatchBlockDisconnect(t *testing.T) {
+	ts := newLimitServer(20)
+	defer ts.Close()
+
+	client := NewClient(&Config{
+		BaseURL:  ts.URL + "/1.1/",
+		Dispatch: DispatchConfig{QueueSize: 2},
+	})
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	client.HandleFunc("limit", func(s *Stream) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- client.Public.Sample()
+	}()
+	<-started
+	client.Disconnect()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Sample returned %v after Disconnect, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sample still blocked on a full queue after Disconnect")
+	}
+}
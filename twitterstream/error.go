@@ -1,21 +1,65 @@
 package twitterstream
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 )
 
-type ErrorReponse struct {
+// Errors matched with errors.Is by the errors returned by streams.
+var (
+	// ErrUnauthorized matches 401 responses.
+	ErrUnauthorized = errors.New("twitterstream: unauthorized")
+
+	// ErrForbidden matches 403 responses.
+	ErrForbidden = errors.New("twitterstream: forbidden")
+
+	// ErrRateLimited matches 420 and 429 responses.
+	ErrRateLimited = errors.New("twitterstream: rate limited")
+
+	// ErrDisconnected matches a *DisconnectError.
+	ErrDisconnected = errors.New("twitterstream: disconnected")
+)
+
+// maxErrorBodySize represents the maximum number of bytes of an error
+// response body kept in ErrorResponse.
+const maxErrorBodySize = 64 << 10
+
+// ErrorResponse is returned for responses with a non-2xx status. It
+// matches ErrUnauthorized, ErrForbidden, ErrRateLimited or
+// ErrFilterTooLong (413) with errors.Is.
+type ErrorResponse struct {
 	Response *http.Response
 	Message  string
+
+	// Body of the response, which has already been read and closed
+	Body []byte
+
+	// Errors parsed from a Twitter JSON error body
+	Errors []APIError
+}
+
+// ErrorReponse is the former, misspelled name of ErrorResponse.
+//
+// Deprecated: Use ErrorResponse.
+type ErrorReponse = ErrorResponse
+
+// APIError represents an entry of the errors list in a Twitter JSON
+// error body.
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
 }
 
+// CheckResponse returns an *ErrorResponse if r does not have a 2xx
+// status. The body of such responses is read and closed.
 func CheckResponse(r *http.Response) error {
 	if c := r.StatusCode; 200 <= c && c <= 299 {
 		return nil
 	}
-	err := &ErrorReponse{Response: r}
+	err := &ErrorResponse{Response: r}
 
 	var msg string
 	switch r.StatusCode {
@@ -29,7 +73,7 @@ func CheckResponse(r *http.Response) error {
 		msg = "A parameter list is too long"
 	case http.StatusRequestedRangeNotSatisfiable:
 		msg = "Range Unacceptable"
-	case 420:
+	case 420, http.StatusTooManyRequests:
 		msg = "Rate Limited"
 	default:
 		msg = "Unknown"
@@ -37,11 +81,100 @@ func CheckResponse(r *http.Response) error {
 
 	err.Message = msg
 
+	if r.Body != nil {
+		err.Body, _ = io.ReadAll(io.LimitReader(r.Body, maxErrorBodySize))
+		r.Body.Close()
+
+		var payload struct {
+			Errors []APIError `json:"errors"`
+		}
+		if json.Unmarshal(err.Body, &payload) == nil {
+			err.Errors = payload.Errors
+		}
+	}
+
 	return err
 }
 
-func (e *ErrorReponse) Error() string {
-	defer e.Response.Body.Close()
-	body, _ := ioutil.ReadAll(e.Response.Body)
-	return fmt.Sprintf("twitterstream: response error: %v, response body: %v", e.Message, string(body))
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("twitterstream: response error: %v, response body: %v", e.Message, string(e.Body))
+}
+
+// Is reports whether the response status matches target.
+func (e *ErrorResponse) Is(target error) bool {
+	switch e.Response.StatusCode {
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case 420, http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case http.StatusRequestEntityTooLarge:
+		return target == ErrFilterTooLong
+	}
+	return false
+}
+
+// Retryable reports whether reconnecting may succeed. Responses
+// rejecting the request itself, such as bad credentials or
+// parameters, are not retryable.
+func (e *ErrorResponse) Retryable() bool {
+	switch e.Response.StatusCode {
+	case http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusNotFound,
+		http.StatusNotAcceptable,
+		http.StatusRequestEntityTooLarge,
+		http.StatusRequestedRangeNotSatisfiable:
+		return false
+	}
+	return true
+}
+
+// DisconnectError is returned when Twitter closed the stream after
+// sending a disconnect message. It matches ErrDisconnected with
+// errors.Is.
+type DisconnectError struct {
+	Disconnect *Disconnect
+}
+
+func (e *DisconnectError) Error() string {
+	return fmt.Sprintf("twitterstream: disconnected (code %d): %v", e.Disconnect.Code, e.Disconnect.ReasonByCode())
+}
+
+// Is reports whether target is ErrDisconnected.
+func (e *DisconnectError) Is(target error) bool {
+	return target == ErrDisconnected
+}
+
+// Retryable reports whether reconnecting may succeed, which is the
+// case when Twitter closed the stream for its own reasons, such as a
+// shutdown or a stall, rather than because of the client.
+func (e *DisconnectError) Retryable() bool {
+	switch e.Disconnect.Code {
+	case 1, 4, 10, 11, 12:
+		return true
+	}
+	return false
+}
+
+// FrameError is returned when a delimited=length stream carries a
+// length prefix that is not a valid message length.
+type FrameError struct {
+	Prefix []byte
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("twitterstream: invalid message length prefix %q", e.Prefix)
+}
+
+// PanicError is reported to Config.OnHandlerError when a handler
+// panics.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("twitterstream: handler panic: %v", e.Value)
 }
@@ -0,0 +1,74 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type CheckResponseTest struct {
+	status    int
+	is        error
+	retryable bool
+}
+
+var checkResponseTests = []CheckResponseTest{
+	{http.StatusUnauthorized, ErrUnauthorized, false},
+	{http.StatusForbidden, ErrForbidden, false},
+	{420, ErrRateLimited, true},
+	{http.StatusTooManyRequests, ErrRateLimited, true},
+	{http.StatusRequestEntityTooLarge, ErrFilterTooLong, false},
+	{http.StatusServiceUnavailable, nil, true},
+}
+
+func TestCheckResponse(t *testing.T) {
+	sentinels := []error{ErrUnauthorized, ErrForbidden, ErrRateLimited, ErrFilterTooLong, ErrDisconnected, ErrStall}
+
+	for _, tt := range checkResponseTests {
+		body := `{"errors":[{"code":32,"message":"Could not authenticate you."}]}`
+		r := &http.Response{
+			StatusCode: tt.status,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+		err := fmt.Errorf("wrapped: %w", CheckResponse(r))
+
+		for _, s := range sentinels {
+			if got, want := errors.Is(err, s), s == tt.is; got != want {
+				t.Errorf("%d: errors.Is(err, %v) = %v, want %v", tt.status, s, got, want)
+			}
+		}
+
+		var e *ErrorResponse
+		if !errors.As(err, &e) {
+			t.Fatalf("%d: error %v is not an *ErrorResponse", tt.status, err)
+		}
+		if e.Retryable() != tt.retryable {
+			t.Errorf("%d: Retryable() = %v, want %v", tt.status, e.Retryable(), tt.retryable)
+		}
+		if len(e.Errors) != 1 || e.Errors[0].Code != 32 || e.Errors[0].Message != "Could not authenticate you." {
+			t.Errorf("%d: Errors = %+v", tt.status, e.Errors)
+		}
+		if first, second := e.Error(), e.Error(); first != second || !strings.Contains(first, body) {
+			t.Errorf("%d: Error() = %q then %q, want both to contain the body", tt.status, first, second)
+		}
+	}
+}
+
+func TestDisconnectErrorIs(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &DisconnectError{Disconnect: &Disconnect{Code: 7}})
+	if !errors.Is(err, ErrDisconnected) {
+		t.Errorf("errors.Is(%v, ErrDisconnected) = false", err)
+	}
+	var d *DisconnectError
+	if !errors.As(err, &d) || d.Retryable() {
+		t.Errorf("code 7 disconnect is retryable")
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Limits of the statuses/filter endpoint.
+// See https://dev.twitter.com/docs/streaming-apis/parameters
+const (
+	// MaxTrackKeywords represents maximum number of track keywords
+	MaxTrackKeywords = 400
+
+	// MaxKeywordLength represents maximum length of a track keyword
+	// in bytes
+	MaxKeywordLength = 60
+
+	// MaxFollowIDs represents maximum number of user IDs to follow
+	MaxFollowIDs = 5000
+
+	// MaxLocations represents maximum number of location boxes
+	MaxLocations = 25
+)
+
+// ErrFilterTooLong is returned when filter parameters exceed the
+// limits of the streaming API, which the endpoint rejects with 413.
+var ErrFilterTooLong = errors.New("twitterstream: filter parameters too long")
+
+// FilterLevel represents the minimum filter_level of tweets
+// delivered by a filter stream.
+type FilterLevel string
+
+const (
+	FilterLevelNone   FilterLevel = "none"
+	FilterLevelLow    FilterLevel = "low"
+	FilterLevelMedium FilterLevel = "medium"
+)
+
+// FilterParams represents parameters of PublicStreams.FilterWith.
+// At least one of Track, Follow or Locations must be set.
+type FilterParams struct {
+	// Keywords to track. A keyword is one or more terms separated
+	// by spaces and cannot contain commas.
+	Track []string
+
+	// User IDs whose tweets should be delivered.
+	Follow []int64
+
+	// Locations to filter by. Only the south-west and north-east
+	// corners of each box are sent, see NewBoundingBox.
+	Locations []BoundingBox
+
+	// Languages (BCP 47) of tweets to deliver.
+	Language []string
+
+	// Minimum filter level of tweets to deliver.
+	FilterLevel FilterLevel
+
+	// Number of messages to backfill, which requires elevated
+	// access. Zero means no backfill.
+	Count int
+}
+
+// NewBoundingBox returns a BoundingBox with the given south-west and
+// north-east corners, suitable for FilterParams.Locations.
+func NewBoundingBox(swLng, swLat, neLng, neLat float64) BoundingBox {
+	return BoundingBox{
+		Type: "Polygon",
+		Coordinates: []llList{{
+			{swLng, swLat},
+			{swLng, neLat},
+			{neLng, neLat},
+			{neLng, swLat},
+		}},
+	}
+}
+
+// corners returns the south-west and north-east corners of the box.
+func (b *BoundingBox) corners() (sw, ne ll, err error) {
+	n := 0
+	for _, ring := range b.Coordinates {
+		for _, p := range ring {
+			if n == 0 {
+				sw, ne = p, p
+			}
+			sw[0], sw[1] = min(sw[0], p[0]), min(sw[1], p[1])
+			ne[0], ne[1] = max(ne[0], p[0]), max(ne[1], p[1])
+			n++
+		}
+	}
+	if n == 0 {
+		return sw, ne, errors.New("twitterstream: empty bounding box")
+	}
+	return sw, ne, nil
+}
+
+// Validate checks p against the limits of the streaming API.
+// Parameters over a limit return an error wrapping ErrFilterTooLong.
+func (p *FilterParams) Validate() error {
+	_, err := p.body()
+	return err
+}
+
+// body validates p and returns it as a filter request body.
+func (p *FilterParams) body() (map[string]string, error) {
+	if len(p.Track) == 0 && len(p.Follow) == 0 && len(p.Locations) == 0 {
+		return nil, errors.New("twitterstream: filter needs at least one of track, follow or locations")
+	}
+	if n := len(p.Track); n > MaxTrackKeywords {
+		return nil, fmt.Errorf("%w: %d track keywords, maximum is %d", ErrFilterTooLong, n, MaxTrackKeywords)
+	}
+	if n := len(p.Follow); n > MaxFollowIDs {
+		return nil, fmt.Errorf("%w: %d follow IDs, maximum is %d", ErrFilterTooLong, n, MaxFollowIDs)
+	}
+	if n := len(p.Locations); n > MaxLocations {
+		return nil, fmt.Errorf("%w: %d locations, maximum is %d", ErrFilterTooLong, n, MaxLocations)
+	}
+
+	body := make(map[string]string)
+
+	if len(p.Track) > 0 {
+		for _, k := range p.Track {
+			if len(k) > MaxKeywordLength {
+				return nil, fmt.Errorf("%w: keyword %q is %d bytes, maximum is %d", ErrFilterTooLong, k, len(k), MaxKeywordLength)
+			}
+			if strings.TrimFunc(k, unicode.IsSpace) == "" || strings.Contains(k, ",") {
+				return nil, fmt.Errorf("twitterstream: invalid track keyword %q", k)
+			}
+		}
+		body["track"] = strings.Join(p.Track, ",")
+	}
+
+	if len(p.Follow) > 0 {
+		ids := make([]string, len(p.Follow))
+		for i, id := range p.Follow {
+			ids[i] = strconv.FormatInt(id, 10)
+		}
+		body["follow"] = strings.Join(ids, ",")
+	}
+
+	if len(p.Locations) > 0 {
+		var coords []string
+		for i := range p.Locations {
+			sw, ne, err := p.Locations[i].corners()
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range []float64{sw[0], sw[1], ne[0], ne[1]} {
+				coords = append(coords, strconv.FormatFloat(f, 'f', -1, 64))
+			}
+		}
+		body["locations"] = strings.Join(coords, ",")
+	}
+
+	if len(p.Language) > 0 {
+		body["language"] = strings.Join(p.Language, ",")
+	}
+
+	switch p.FilterLevel {
+	case "":
+	case FilterLevelNone, FilterLevelLow, FilterLevelMedium:
+		body["filter_level"] = string(p.FilterLevel)
+	default:
+		return nil, fmt.Errorf("twitterstream: invalid filter level %q", p.FilterLevel)
+	}
+
+	if p.Count != 0 {
+		body["count"] = strconv.Itoa(p.Count)
+	}
+
+	body["stall_warnings"] = "true"
+	return body, nil
+}
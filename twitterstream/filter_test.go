@@ -0,0 +1,137 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type FilterParamsTest struct {
+	in  *FilterParams
+	out map[string]string
+}
+
+var filterParamsTests = []FilterParamsTest{
+	{
+		&FilterParams{Track: []string{"jakarta", "macet total"}},
+		map[string]string{
+			"track":          "jakarta,macet total",
+			"stall_warnings": "true",
+		},
+	},
+	{
+		&FilterParams{
+			Follow:      []int64{12, 1106913162},
+			Language:    []string{"en", "id"},
+			FilterLevel: FilterLevelLow,
+			Count:       -1000,
+		},
+		map[string]string{
+			"follow":         "12,1106913162",
+			"language":       "en,id",
+			"filter_level":   "low",
+			"count":          "-1000",
+			"stall_warnings": "true",
+		},
+	},
+	{
+		&FilterParams{
+			Locations: []BoundingBox{
+				NewBoundingBox(-122.75, 36.8, -121.75, 37.8),
+				NewBoundingBox(-74, 40, -73, 41),
+			},
+		},
+		map[string]string{
+			"locations":      "-122.75,36.8,-121.75,37.8,-74,40,-73,41",
+			"stall_warnings": "true",
+		},
+	},
+}
+
+func TestFilterParamsBody(t *testing.T) {
+	for _, tt := range filterParamsTests {
+		actual, err := tt.in.body()
+		if err != nil {
+			t.Errorf("body(%+v) returned error %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.out) {
+			t.Errorf("body(%+v) = %v, want %v", tt.in, actual, tt.out)
+		}
+	}
+}
+
+func TestBoundingBoxCorners(t *testing.T) {
+	// A place bounding box, as delivered in tweets.
+	b := BoundingBox{
+		Type: "Polygon",
+		Coordinates: []llList{{
+			{106.68, -6.37},
+			{106.68, -6.08},
+			{106.97, -6.08},
+			{106.97, -6.37},
+		}},
+	}
+	sw, ne, err := b.corners()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sw != (ll{106.68, -6.37}) || ne != (ll{106.97, -6.08}) {
+		t.Errorf("corners() = %v, %v, want [106.68 -6.37], [106.97 -6.08]", sw, ne)
+	}
+}
+
+func repeat(n int, f func(i int)) {
+	for i := 0; i < n; i++ {
+		f(i)
+	}
+}
+
+func TestFilterParamsValidate(t *testing.T) {
+	tooManyTrack := new(FilterParams)
+	repeat(MaxTrackKeywords+1, func(i int) {
+		tooManyTrack.Track = append(tooManyTrack.Track, "k")
+	})
+	tooManyFollow := new(FilterParams)
+	repeat(MaxFollowIDs+1, func(i int) {
+		tooManyFollow.Follow = append(tooManyFollow.Follow, int64(i))
+	})
+	tooManyLocations := new(FilterParams)
+	repeat(MaxLocations+1, func(i int) {
+		tooManyLocations.Locations = append(tooManyLocations.Locations, NewBoundingBox(0, 0, 1, 1))
+	})
+	longKeyword := &FilterParams{Track: []string{strings.Repeat("a", MaxKeywordLength+1)}}
+
+	for _, p := range []*FilterParams{tooManyTrack, tooManyFollow, tooManyLocations, longKeyword} {
+		if err := p.Validate(); !errors.Is(err, ErrFilterTooLong) {
+			t.Errorf("Validate() = %v, want %v", err, ErrFilterTooLong)
+		}
+	}
+
+	invalid := []*FilterParams{
+		{},
+		{Track: []string{"a,b"}},
+		{Track: []string{" "}},
+		{Track: []string{"a"}, FilterLevel: "high"},
+		{Locations: []BoundingBox{{}}},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil || errors.Is(err, ErrFilterTooLong) {
+			t.Errorf("Validate(%+v) = %v, want invalid parameter error", p, err)
+		}
+	}
+
+	limits := &FilterParams{Track: []string{strings.Repeat("a", MaxKeywordLength)}}
+	repeat(MaxFollowIDs, func(i int) {
+		limits.Follow = append(limits.Follow, 1<<62+int64(i))
+	})
+	if err := limits.Validate(); err != nil {
+		t.Errorf("Validate() at the limits = %v, want nil", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Framing represents how messages are delimited in a stream.
+type Framing int
+
+const (
+	// FramingNewline splits messages on newlines. This is the
+	// default.
+	FramingNewline Framing = iota
+
+	// FramingLength requests delimited=length, so every message is
+	// preceded by its length in bytes and read as an exact frame.
+	FramingLength
+)
+
+// maxFrameSize represents the largest length prefix accepted in a
+// delimited=length stream. Real messages are far smaller, larger
+// prefixes mean the stream is corrupt.
+const maxFrameSize = 1 << 20
+
+// frameReader reads messages from a stream body.
+type frameReader interface {
+	// next returns the next message without surrounding whitespace,
+	// skipping keep-alive newlines.
+	next() ([]byte, error)
+}
+
+func newFrameReader(framing Framing, r io.Reader) frameReader {
+	br := bufio.NewReader(r)
+	if framing == FramingLength {
+		return &lengthReader{r: br}
+	}
+	return &lineReader{r: br}
+}
+
+// lineReader reads newline delimited messages.
+type lineReader struct {
+	r *bufio.Reader
+}
+
+func (f *lineReader) next() ([]byte, error) {
+	for {
+		line, err := f.r.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return line, nil
+		}
+	}
+}
+
+// lengthReader reads messages preceded by their length.
+type lengthReader struct {
+	r *bufio.Reader
+}
+
+func (f *lengthReader) next() ([]byte, error) {
+	for {
+		prefix, err := f.r.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		prefix = bytes.TrimSpace(prefix)
+		if len(prefix) == 0 {
+			continue
+		}
+
+		n, err := strconv.Atoi(string(prefix))
+		if err != nil || n <= 0 || n > maxFrameSize {
+			return nil, &FrameError{Prefix: prefix}
+		}
+
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(f.r, frame); err != nil {
+			return nil, err
+		}
+		if frame = bytes.TrimSpace(frame); len(frame) > 0 {
+			return frame, nil
+		}
+	}
+}
+
+// frameReader returns the frameReader for the stream response r,
+// whose body is read from body. Compressed bodies are decompressed
+// as they arrive, so each message is returned as soon as the server
+// has flushed it.
+func (c *Client) frameReader(r *http.Response, body io.Reader) (frameReader, error) {
+	var err error
+	switch r.Header.Get("Content-Encoding") {
+	case "gzip":
+		body, err = gzip.NewReader(body)
+	case "deflate":
+		body, err = zlib.NewReader(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newFrameReader(c.config.Framing, body), nil
+}
+
+// framed returns urlStr and body with the delimited parameter
+// added for the configured framing. body is copied, not modified.
+func (c *Client) framed(urlStr string, body map[string]string) (string, map[string]string, error) {
+	if c.config.Framing != FramingLength {
+		return urlStr, body, nil
+	}
+
+	if body != nil {
+		b := make(map[string]string, len(body)+1)
+		for k, v := range body {
+			b[k] = v
+		}
+		b["delimited"] = "length"
+		return urlStr, b, nil
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return "", nil, err
+	}
+	q := u.Query()
+	q.Set("delimited", "length")
+	u.RawQuery = q.Encode()
+	return u.String(), nil, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// lengthFramed returns msgs framed the way delimited=length streams
+// are, with keep-alive newlines in between.
+func lengthFramed(msgs ...string) string {
+	var s string
+	for _, m := range msgs {
+		m += "\r\n"
+		s += fmt.Sprintf("\r\n%d\r\n%s", len(m), m)
+	}
+	return s
+}
+
+func TestLengthReader(t *testing.T) {
+	msgs := []string{
+		`{"limit":{"track":1}}`,
+		// An escaped newline inside the text and a raw one between
+		// keys, both part of the same frame.
+		"{\"text\":\"line\\nbreak\",\n\"user\":{\"id\":1}}",
+		`{"limit":{"track":2}}`,
+	}
+
+	frames := newFrameReader(FramingLength, strings.NewReader(lengthFramed(msgs...)))
+	for _, want := range msgs {
+		actual, err := frames.next()
+		if err != nil {
+			t.Fatalf("next() returned error %v", err)
+		}
+		if string(actual) != want {
+			t.Errorf("next() = %q, want %q", actual, want)
+		}
+	}
+	if _, err := frames.next(); err != io.EOF {
+		t.Errorf("next() at end = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestLengthReaderCorrupt(t *testing.T) {
+	for _, in := range []string{"abc\r\n{}", "-5\r\n{}", "99999999999\r\n{}"} {
+		frames := newFrameReader(FramingLength, strings.NewReader(in))
+		_, err := frames.next()
+		if _, ok := err.(*FrameError); !ok {
+			t.Errorf("next() on %q = %v, want *FrameError", in, err)
+		}
+	}
+
+	frames := newFrameReader(FramingLength, strings.NewReader("40\r\n{\"limit\":"))
+	if _, err := frames.next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("next() on truncated frame = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestFramingLengthRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if d := r.Form.Get("delimited"); d != "length" {
+			t.Errorf("%v %v delimited = %q, want length", r.Method, r.URL.Path, d)
+		}
+		fmt.Fprint(w, lengthFramed(`{"limit":{"track":7}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{
+		BaseURL:       ts.URL + "/1.1/",
+		MaxReconnects: -1,
+		Framing:       FramingLength,
+	})
+
+	tracks := make(chan int64, 2)
+	client.HandleFunc("limit", func(s *Stream) {
+		tracks <- s.LimitNotice.Limit.Track
+	})
+
+	if err := client.Public.Sample(); err != io.EOF {
+		t.Errorf("Sample returned %v, want %v", err, io.EOF)
+	}
+	if err := client.Public.Filter(map[string]string{"track": "go"}); err != io.EOF {
+		t.Errorf("Filter returned %v, want %v", err, io.EOF)
+	}
+	for i := 0; i < 2; i++ {
+		if track := <-tracks; track != 7 {
+			t.Errorf("limit track = %d, want 7", track)
+		}
+	}
+}
+
+func TestFramingLengthNewRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d := r.URL.Query().Get("delimited"); d != "length" {
+			t.Errorf("delimited = %q, want length", d)
+		}
+		fmt.Fprint(w, lengthFramed(`{"limit":{"track":1}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{
+		BaseURL: ts.URL + "/1.1/",
+		Framing: FramingLength,
+	})
+
+	tracks := make(chan int64, 1)
+	client.HandleFunc("limit", func(s *Stream) {
+		tracks <- s.LimitNotice.Limit.Track
+	})
+
+	req, err := client.NewRequest("GET", "statuses/sample.json", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do returned error %v", err)
+	}
+	if err := client.DispatchResponse(resp); err != io.EOF {
+		t.Errorf("DispatchResponse returned %v, want %v", err, io.EOF)
+	}
+	if track := <-tracks; track != 1 {
+		t.Errorf("limit track = %d, want 1", track)
+	}
+}
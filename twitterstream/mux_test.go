@@ -0,0 +1,205 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMuxMiddleware(t *testing.T) {
+	client := NewClient(&Config{})
+
+	var calls []string
+	trace := func(name string) func(Handler) Handler {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(s *Stream) {
+				calls = append(calls, name+" "+s.Type)
+				next.ProcessStream(s)
+			})
+		}
+	}
+	client.Use(trace("outer"), trace("inner"))
+	client.HandleFunc("limit", func(s *Stream) {
+		calls = append(calls, "first")
+	})
+	client.HandleFunc("limit", func(s *Stream) {
+		calls = append(calls, "second")
+	})
+
+	client.handleStream(&Stream{Type: "limit"})
+	// Middleware also wraps streams without a handler.
+	client.handleStream(&Stream{Type: "warning"})
+
+	want := []string{"outer limit", "inner limit", "first", "second", "outer warning", "inner warning"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
+
+func TestMuxMiddlewareSkip(t *testing.T) {
+	client := NewClient(&Config{})
+
+	handled := 0
+	client.HandleFunc("limit", func(s *Stream) {
+		handled++
+	})
+	// Middleware can drop messages by not calling next.
+	client.Use(func(next Handler) Handler {
+		return HandlerFunc(func(s *Stream) {
+			if s.LimitNotice != nil {
+				next.ProcessStream(s)
+			}
+		})
+	})
+
+	client.handleStream(&Stream{Type: "limit"})
+	client.handleStream(&Stream{Type: "limit", LimitNotice: &LimitNotice{}})
+
+	if handled != 1 {
+		t.Errorf("handler called %d times, want 1", handled)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	type report struct {
+		streamType string
+		raw        string
+		err        error
+	}
+	var reports []report
+	errBad := errors.New("bad message")
+
+	client := NewClient(&Config{
+		OnHandlerError: func(streamType string, raw []byte, err error) {
+			reports = append(reports, report{streamType, string(raw), err})
+		},
+	})
+
+	called := false
+	client.HandleErrorFunc("limit", func(s *Stream) error {
+		return errBad
+	})
+	client.HandleFunc("limit", func(s *Stream) {
+		panic("boom")
+	})
+	client.HandleFunc("limit", func(s *Stream) {
+		called = true
+	})
+
+	client.handleStream(&Stream{Type: "limit", Raw: []byte(`{"limit":{}}`)})
+
+	if !called {
+		t.Error("handler after failing handlers not called")
+	}
+	if len(reports) != 2 {
+		t.Fatalf("OnHandlerError called %d times, want 2", len(reports))
+	}
+	if r := reports[0]; r.streamType != "limit" || r.raw != `{"limit":{}}` || r.err != errBad {
+		t.Errorf("first report = %+v, want limit, raw message and %v", r, errBad)
+	}
+	var pe *PanicError
+	if !errors.As(reports[1].err, &pe) || pe.Value != "boom" || len(pe.Stack) == 0 {
+		t.Errorf("second report error = %v, want *PanicError with value boom", reports[1].err)
+	}
+}
+
+func TestCatchAllAndUnknown(t *testing.T) {
+	client := NewClient(&Config{})
+
+	var calls []string
+	client.HandleFunc("*", func(s *Stream) {
+		calls = append(calls, "all "+s.Type)
+	})
+	client.HandleFunc("limit", func(s *Stream) {
+		calls = append(calls, "limit")
+	})
+	var unknown []string
+	client.HandleUnknown(func(s *Stream) {
+		unknown = append(unknown, string(s.Raw))
+	})
+
+	conn, _ := client.newConnection(nil)
+	for _, raw := range []string{
+		`{"limit":{"track":1}}`,
+		`{"brand_new":{"id":1}}`,
+	} {
+		if s := client.streamSwitcher(conn, []byte(raw)); s != nil {
+			client.handleStream(s)
+		}
+	}
+
+	want := []string{"limit", "all limit", "all unknown"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+	if len(unknown) != 1 || unknown[0] != `{"brand_new":{"id":1}}` {
+		t.Errorf("unknown handler received %q", unknown)
+	}
+}
+
+type EventRouteTest struct {
+	raw  string
+	conn bool
+	want string
+}
+
+var eventRouteTests = []EventRouteTest{
+	{`{"event":"favorite","source":{"id":1}}`, false, "client favorite"},
+	{`{"event":"unfollow","source":{"id":1}}`, false, "client event"},
+	{`{"for_user":1,"message":{"event":"favorite","source":{"id":1}}}`, false, "client favorite"},
+	{`{"for_user":1,"message":{"event":"unfollow","source":{"id":1}}}`, false, "client for_user"},
+	{`{"for_user":1,"message":{"friends":[2,3]}}`, false, "client for_user"},
+
+	// Connection handlers for the event name or the type win over
+	// client handlers, even those for the event name.
+	{`{"event":"follow","source":{"id":1}}`, true, "conn follow"},
+	{`{"event":"favorite","source":{"id":1}}`, true, "conn event"},
+	{`{"for_user":1,"message":{"event":"favorite","source":{"id":1}}}`, true, "client favorite"},
+}
+
+func TestEventRouting(t *testing.T) {
+	client := NewClient(&Config{})
+
+	var calls []string
+	record := func(name string) func(*Stream) {
+		return func(*Stream) {
+			calls = append(calls, name)
+		}
+	}
+	client.HandleFunc("favorite", record("client favorite"))
+	client.HandleFunc("follow", record("client follow"))
+	client.HandleFunc("event", record("client event"))
+	client.HandleFunc("for_user", record("client for_user"))
+
+	plain, _ := client.newConnection(nil)
+	scoped, err := client.newConnection([]ConnOption{
+		WithHandler("follow", record("conn follow")),
+		WithHandler("event", record("conn event")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range eventRouteTests {
+		conn := plain
+		if tt.conn {
+			conn = scoped
+		}
+		calls = nil
+		s := client.streamSwitcher(conn, []byte(tt.raw))
+		if s == nil {
+			t.Errorf("streamSwitcher(%s) = nil", tt.raw)
+			continue
+		}
+		s.conn = conn
+		client.handleStream(s)
+		if len(calls) != 1 || calls[0] != tt.want {
+			t.Errorf("%s (conn handlers %v) went to %q, want %q", tt.raw, tt.conn, calls, tt.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package oauth obtains OAuth 1.0a user tokens for twitterstream
+// with the three-legged PIN or callback flow.
+//
+// A request token is obtained with RequestToken, the user
+// authorizes it at AuthorizationURL and the verifier they get, as a
+// PIN or through the callback, is exchanged for an access token with
+// AccessToken.
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gedex/go-twitterstream/twitterstream"
+)
+
+const (
+	// DefaultRequestTokenURL represents default request token endpoint
+	DefaultRequestTokenURL = "https://api.twitter.com/oauth/request_token"
+
+	// DefaultAuthorizeURL represents default authorization page
+	DefaultAuthorizeURL = "https://api.twitter.com/oauth/authorize"
+
+	// DefaultAccessTokenURL represents default access token endpoint
+	DefaultAccessTokenURL = "https://api.twitter.com/oauth/access_token"
+
+	// OOB is the callback of the PIN flow, where the user is shown a
+	// verifier to enter instead of being redirected.
+	OOB = "oob"
+)
+
+// Config represents an application obtaining user tokens.
+type Config struct {
+	ConsumerKey    string
+	ConsumerSecret string
+
+	// URL the user is redirected to after authorizing, OOB for the
+	// PIN flow if empty
+	CallbackURL string
+
+	// Endpoints, the Default*URL constants if empty
+	RequestTokenURL string
+	AuthorizeURL    string
+	AccessTokenURL  string
+
+	// HTTP client used for token requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// RequestToken represents a temporary token to be authorized by the
+// user.
+type RequestToken struct {
+	Token  string
+	Secret string
+}
+
+// AccessToken represents a user token, to be used as
+// twitterstream.Config OAuthToken and OAuthTokenSecret.
+type AccessToken struct {
+	Token      string
+	Secret     string
+	UserID     int64
+	ScreenName string
+}
+
+// RequestToken obtains a request token for the callback of c.
+func (c *Config) RequestToken(ctx context.Context) (*RequestToken, error) {
+	callback := c.CallbackURL
+	if callback == "" {
+		callback = OOB
+	}
+
+	v, err := c.post(ctx, orDefault(c.RequestTokenURL, DefaultRequestTokenURL), "", map[string]string{
+		"oauth_callback": callback,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if v.Get("oauth_callback_confirmed") != "true" {
+		return nil, errors.New("twitterstream/oauth: callback not confirmed")
+	}
+	return &RequestToken{
+		Token:  v.Get("oauth_token"),
+		Secret: v.Get("oauth_token_secret"),
+	}, nil
+}
+
+// AuthorizationURL returns the URL of the page where the user
+// authorizes rt.
+func (c *Config) AuthorizationURL(rt *RequestToken) string {
+	u := orDefault(c.AuthorizeURL, DefaultAuthorizeURL)
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	return u + sep + url.Values{"oauth_token": {rt.Token}}.Encode()
+}
+
+// AccessToken exchanges rt, authorized by the user, for an access
+// token. verifier is the PIN shown to the user or the oauth_verifier
+// of the callback, see ParseCallback.
+func (c *Config) AccessToken(ctx context.Context, rt *RequestToken, verifier string) (*AccessToken, error) {
+	v, err := c.post(ctx, orDefault(c.AccessTokenURL, DefaultAccessTokenURL), rt.Secret, map[string]string{
+		"oauth_token":    rt.Token,
+		"oauth_verifier": verifier,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	at := &AccessToken{
+		Token:      v.Get("oauth_token"),
+		Secret:     v.Get("oauth_token_secret"),
+		ScreenName: v.Get("screen_name"),
+	}
+	if id := v.Get("user_id"); id != "" {
+		if at.UserID, err = strconv.ParseInt(id, 10, 64); err != nil {
+			return nil, fmt.Errorf("twitterstream/oauth: invalid user_id %q", id)
+		}
+	}
+	if at.Token == "" || at.Secret == "" {
+		return nil, errors.New("twitterstream/oauth: no access token in response")
+	}
+	return at, nil
+}
+
+// ParseCallback returns the request token and verifier passed to the
+// callback URL in u.
+func ParseCallback(u *url.URL) (token, verifier string, err error) {
+	q := u.Query()
+	if q.Has("denied") {
+		return "", "", errors.New("twitterstream/oauth: authorization denied")
+	}
+	token, verifier = q.Get("oauth_token"), q.Get("oauth_verifier")
+	if token == "" || verifier == "" {
+		return "", "", errors.New("twitterstream/oauth: callback without token or verifier")
+	}
+	return token, verifier, nil
+}
+
+// post sends a signed POST request without body to endpoint, with
+// the extra OAuth parameters in oauth, and returns the form encoded
+// response.
+func (c *Config) post(ctx context.Context, endpoint, tokenSecret string, oauth map[string]string) (url.Values, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	op := map[string]string{
+		"oauth_consumer_key":     c.ConsumerKey,
+		"oauth_nonce":            twitterstream.Nonce(42),
+		"oauth_signature_method": "HMAC-SHA1",
+		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
+		"oauth_version":          "1.0",
+	}
+	for k, v := range oauth {
+		op[k] = v
+	}
+
+	rp := &twitterstream.RequestParams{
+		Method:   "POST",
+		Endpoint: u.Scheme + "://" + u.Host + u.Path,
+		Query:    make(map[string]string),
+		OAuth:    make(map[string]string, len(op)),
+	}
+	for k := range u.Query() {
+		rp.Query[twitterstream.Escape(k)] = twitterstream.Escape(u.Query().Get(k))
+	}
+	for k, v := range op {
+		rp.OAuth[twitterstream.Escape(k)] = twitterstream.Escape(v)
+	}
+
+	signature, err := twitterstream.Signature(c.ConsumerSecret, tokenSecret, twitterstream.SignatureBaseString(rp))
+	if err != nil {
+		return nil, err
+	}
+	rp.OAuth["oauth_signature"] = twitterstream.Escape(signature)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authorizationHeader(rp.OAuth))
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := twitterstream.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return url.ParseQuery(string(body))
+}
+
+// authorizationHeader returns the OAuth Authorization header with
+// the escaped parameters op.
+func authorizationHeader(op map[string]string) string {
+	keys := make([]string, 0, len(op))
+	for k := range op {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=\"%s\"", k, op[k])
+	}
+	return "OAuth " + strings.Join(parts, ", ")
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,154 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gedex/go-twitterstream/twitterstream"
+)
+
+const (
+	consumerKey    = "consumer"
+	consumerSecret = "consumer-secret"
+	requestToken   = "request"
+	requestSecret  = "request-secret"
+	pin            = "1234567"
+)
+
+// verified returns the OAuth parameters of r, unescaped, if r is
+// signed with the consumer secret and tokenSecret.
+func verified(r *http.Request, tokenSecret string) (map[string]string, bool) {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "OAuth ")
+	if !ok {
+		return nil, false
+	}
+
+	escaped := make(map[string]string)
+	params := make(map[string]string)
+	for _, p := range strings.Split(auth, ", ") {
+		k, v, _ := strings.Cut(p, "=")
+		v = strings.Trim(v, `"`)
+		escaped[k] = v
+		params[k], _ = url.PathUnescape(v)
+	}
+	signature := params["oauth_signature"]
+	delete(escaped, "oauth_signature")
+
+	rp := &twitterstream.RequestParams{
+		Method:   r.Method,
+		Endpoint: "http://" + r.Host + r.URL.Path,
+		OAuth:    escaped,
+	}
+	want, _ := twitterstream.Signature(consumerSecret, tokenSecret, twitterstream.SignatureBaseString(rp))
+	return params, params["oauth_consumer_key"] == consumerKey && signature == want
+}
+
+func newOAuthServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/request_token", func(w http.ResponseWriter, r *http.Request) {
+		params, ok := verified(r, "")
+		if !ok || params["oauth_callback"] == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "oauth_token=%s&oauth_token_secret=%s&oauth_callback_confirmed=true", requestToken, requestSecret)
+	})
+	mux.HandleFunc("/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		params, ok := verified(r, requestSecret)
+		if !ok || params["oauth_token"] != requestToken || params["oauth_verifier"] != pin {
+			http.Error(w, `{"errors":[{"code":89,"message":"Invalid or expired token."}]}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "oauth_token=6253282-access&oauth_token_secret=access-secret&user_id=6253282&screen_name=twitterapi")
+	})
+	return httptest.NewServer(mux)
+}
+
+func newConfig(ts *httptest.Server) *Config {
+	return &Config{
+		ConsumerKey:     consumerKey,
+		ConsumerSecret:  consumerSecret,
+		RequestTokenURL: ts.URL + "/oauth/request_token",
+		AuthorizeURL:    ts.URL + "/oauth/authorize",
+		AccessTokenURL:  ts.URL + "/oauth/access_token",
+	}
+}
+
+func TestPINFlow(t *testing.T) {
+	ts := newOAuthServer()
+	defer ts.Close()
+
+	c := newConfig(ts)
+	ctx := context.Background()
+
+	rt, err := c.RequestToken(ctx)
+	if err != nil {
+		t.Fatalf("RequestToken returned error %v", err)
+	}
+	if rt.Token != requestToken || rt.Secret != requestSecret {
+		t.Errorf("RequestToken() = %+v", rt)
+	}
+
+	if u, want := c.AuthorizationURL(rt), ts.URL+"/oauth/authorize?oauth_token=request"; u != want {
+		t.Errorf("AuthorizationURL() = %v, want %v", u, want)
+	}
+
+	at, err := c.AccessToken(ctx, rt, pin)
+	if err != nil {
+		t.Fatalf("AccessToken returned error %v", err)
+	}
+	want := AccessToken{Token: "6253282-access", Secret: "access-secret", UserID: 6253282, ScreenName: "twitterapi"}
+	if *at != want {
+		t.Errorf("AccessToken() = %+v, want %+v", *at, want)
+	}
+}
+
+func TestAccessTokenWrongVerifier(t *testing.T) {
+	ts := newOAuthServer()
+	defer ts.Close()
+
+	c := newConfig(ts)
+	_, err := c.AccessToken(context.Background(), &RequestToken{Token: requestToken, Secret: requestSecret}, "0000000")
+
+	var e *twitterstream.ErrorResponse
+	if !errors.As(err, &e) || !errors.Is(err, twitterstream.ErrUnauthorized) {
+		t.Fatalf("AccessToken returned error %v, want an unauthorized *ErrorResponse", err)
+	}
+	if len(e.Errors) != 1 || e.Errors[0].Code != 89 {
+		t.Errorf("Errors = %+v, want code 89", e.Errors)
+	}
+}
+
+type ParseCallbackTest struct {
+	in       string
+	token    string
+	verifier string
+	err      bool
+}
+
+var parseCallbackTests = []ParseCallbackTest{
+	{"https://example.com/cb?oauth_token=request&oauth_verifier=v", "request", "v", false},
+	{"https://example.com/cb?denied=request", "", "", true},
+	{"https://example.com/cb?oauth_token=request", "", "", true},
+}
+
+func TestParseCallback(t *testing.T) {
+	for _, tt := range parseCallbackTests {
+		u, _ := url.Parse(tt.in)
+		token, verifier, err := ParseCallback(u)
+		if token != tt.token || verifier != tt.verifier || (err != nil) != tt.err {
+			t.Errorf("ParseCallback(%v) = %q, %q, %v", tt.in, token, verifier, err)
+		}
+	}
+}
@@ -5,27 +5,46 @@
 
 package twitterstream
 
+import (
+	"context"
+	"net/url"
+)
+
 type PublicStreams struct {
-	client *Client
+	client  *Client
+	baseURL *url.URL
 }
 
 func (s *PublicStreams) Sample() error {
-	u := "statuses/sample.json?stall_warnings=true"
-
-	req, err := s.client.NewRequest("GET", u, nil)
-	if err != nil {
-		return err
-	}
+	return s.SampleContext(context.Background())
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return err
-	}
+// SampleContext is like Sample but stops the stream and returns
+// ctx.Err() as soon as ctx is done.
+func (s *PublicStreams) SampleContext(ctx context.Context) error {
+	return waitConn(s.OpenSample(ctx))
+}
 
-	return s.client.DispatchResponse(resp)
+// OpenSample opens a sample stream that runs until it is closed or
+// ctx is done.
+func (s *PublicStreams) OpenSample(ctx context.Context, opts ...ConnOption) (*Connection, error) {
+	u := "statuses/sample.json?stall_warnings=true"
+	return s.client.open(ctx, s.baseURL, "GET", u, nil, opts)
 }
 
 func (s *PublicStreams) Filter(f map[string]string) error {
+	return s.FilterContext(context.Background(), f)
+}
+
+// FilterContext is like Filter but stops the stream and returns
+// ctx.Err() as soon as ctx is done.
+func (s *PublicStreams) FilterContext(ctx context.Context, f map[string]string) error {
+	return waitConn(s.OpenFilter(ctx, f))
+}
+
+// OpenFilter opens a filter stream that runs until it is closed or
+// ctx is done.
+func (s *PublicStreams) OpenFilter(ctx context.Context, f map[string]string, opts ...ConnOption) (*Connection, error) {
 	u := "statuses/filter.json"
 
 	params := []string{"follow", "track", "locations"}
@@ -37,31 +56,47 @@ func (s *PublicStreams) Filter(f map[string]string) error {
 	}
 	body["stall_warnings"] = "true"
 
-	req, err := s.client.NewRequest("POST", u, body)
-	if err != nil {
-		return err
-	}
+	return s.client.open(ctx, s.baseURL, "POST", u, body, opts)
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return err
-	}
+// FilterWith is like Filter but takes typed parameters, which are
+// validated against the streaming API limits before connecting.
+func (s *PublicStreams) FilterWith(p *FilterParams) error {
+	return s.FilterWithContext(context.Background(), p)
+}
 
-	return s.client.DispatchResponse(resp)
+// FilterWithContext is like FilterWith but stops the stream and
+// returns ctx.Err() as soon as ctx is done.
+func (s *PublicStreams) FilterWithContext(ctx context.Context, p *FilterParams) error {
+	return waitConn(s.OpenFilterWith(ctx, p))
 }
 
-func (s *PublicStreams) Firehose() error {
-	u := "statuses/firehose.json?stall_warnings=true"
+// OpenFilterWith is like OpenFilter but takes typed parameters,
+// which are validated before connecting.
+func (s *PublicStreams) OpenFilterWith(ctx context.Context, p *FilterParams, opts ...ConnOption) (*Connection, error) {
+	u := "statuses/filter.json"
 
-	req, err := s.client.NewRequest("GET", u, nil)
+	body, err := p.body()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return err
-	}
+	return s.client.open(ctx, s.baseURL, "POST", u, body, opts)
+}
+
+func (s *PublicStreams) Firehose() error {
+	return s.FirehoseContext(context.Background())
+}
+
+// FirehoseContext is like Firehose but stops the stream and returns
+// ctx.Err() as soon as ctx is done.
+func (s *PublicStreams) FirehoseContext(ctx context.Context) error {
+	return waitConn(s.OpenFirehose(ctx))
+}
 
-	return s.client.DispatchResponse(resp)
+// OpenFirehose opens a firehose stream that runs until it is closed
+// or ctx is done.
+func (s *PublicStreams) OpenFirehose(ctx context.Context, opts ...ConnOption) (*Connection, error) {
+	u := "statuses/firehose.json?stall_warnings=true"
+	return s.client.open(ctx, s.baseURL, "GET", u, nil, opts)
 }
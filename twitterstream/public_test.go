@@ -0,0 +1,108 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSampleContextCancel(t *testing.T) {
+	connected := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "\r\n")
+		w.(http.Flusher).Flush()
+		close(connected)
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- client.Public.SampleContext(ctx)
+	}()
+
+	<-connected
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("SampleContext returned %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SampleContext did not return after cancel")
+	}
+}
+
+func TestConcurrentStreams(t *testing.T) {
+	paths := make(chan string, 3)
+	newServer := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths <- r.URL.Path
+			fmt.Fprint(w, "\r\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}))
+	}
+	public, user, site := newServer(), newServer(), newServer()
+	defer public.Close()
+	defer user.Close()
+	defer site.Close()
+
+	client := NewClient(&Config{
+		BaseURL:           public.URL + "/public/",
+		UserStreamBaseURL: user.URL + "/user/",
+		SiteStreamBaseURL: site.URL + "/site/",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 3)
+	go func() { done <- client.User.GetContext(ctx, nil) }()
+	go func() { done <- client.Site.GetContext(ctx, map[string]string{"follow": "1"}) }()
+	go func() { done <- client.Public.SampleContext(ctx) }()
+
+	want := map[string]bool{
+		"/public/statuses/sample.json": true,
+		"/user/user.json":              true,
+		"/site/site.json":              true,
+	}
+	for range 3 {
+		select {
+		case p := <-paths:
+			if !want[p] {
+				t.Errorf("unexpected request to %v", p)
+			}
+			delete(want, p)
+		case <-time.After(time.Second):
+			t.Fatalf("streams not connected, missing %v", want)
+		}
+	}
+
+	cancel()
+	for range 3 {
+		if err := <-done; err != context.Canceled {
+			t.Errorf("stream returned %v, want %v", err, context.Canceled)
+		}
+	}
+
+	// Opening user and site streams must leave the public base URL
+	// alone.
+	req, err := client.NewRequest("GET", "statuses/sample.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := req.URL.String(), public.URL+"/public/statuses/sample.json"; got != want {
+		t.Errorf("NewRequest URL = %v, want %v", got, want)
+	}
+}
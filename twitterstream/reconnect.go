@@ -0,0 +1,188 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Backoff schedules as documented in
+// https://dev.twitter.com/docs/streaming-apis/connecting#Reconnecting
+const (
+	// TCP/IP level network errors back off linearly, starting at
+	// 250 milliseconds, up to 16 seconds.
+	tcpBackoffStart = 250 * time.Millisecond
+	tcpBackoffMax   = 16 * time.Second
+
+	// HTTP errors back off exponentially, starting at 5 seconds,
+	// up to 320 seconds.
+	httpBackoffStart = 5 * time.Second
+	httpBackoffMax   = 320 * time.Second
+
+	// HTTP 420 errors back off exponentially, starting at 1 minute.
+	rateLimitBackoffStart = time.Minute
+)
+
+// backoffKind identifies which reconnect schedule applies to an error.
+type backoffKind int
+
+const (
+	// backoffNone means the error is not worth reconnecting for.
+	backoffNone backoffKind = iota
+	backoffTCP
+	backoffHTTP
+	backoffRateLimit
+)
+
+// backoffFor returns the backoff schedule for err. Errors that are
+// not Retryable, such as bad credentials or a stream replaced by
+// another connection, return backoffNone since reconnecting would
+// fail the same way.
+func backoffFor(err error) backoffKind {
+	var r interface{ Retryable() bool }
+	if errors.As(err, &r) && !r.Retryable() {
+		return backoffNone
+	}
+
+	var e *ErrorResponse
+	if !errors.As(err, &e) {
+		return backoffTCP
+	}
+	if errors.Is(e, ErrRateLimited) {
+		return backoffRateLimit
+	}
+	return backoffHTTP
+}
+
+// nextBackoff returns the wait that follows prev in the schedule
+// of kind. A zero prev returns the first wait of the schedule.
+func nextBackoff(kind backoffKind, prev time.Duration) time.Duration {
+	switch kind {
+	case backoffTCP:
+		if prev += tcpBackoffStart; prev > tcpBackoffMax {
+			prev = tcpBackoffMax
+		}
+		return prev
+	case backoffHTTP:
+		if prev == 0 {
+			return httpBackoffStart
+		}
+		if prev *= 2; prev > httpBackoffMax {
+			prev = httpBackoffMax
+		}
+		return prev
+	case backoffRateLimit:
+		if prev == 0 {
+			return rateLimitBackoffStart
+		}
+		return prev * 2
+	}
+	return 0
+}
+
+// connect opens the stream at urlStr, resolved against baseURL, and
+// dispatches it until the client is disconnected or ctx is done.
+// When the connection drops, connect reconnects following Twitter's
+// backoff schedules until MaxReconnects consecutive attempts have
+// failed to deliver any message, in which case the last error is
+// returned.
+func (c *Client) connect(ctx context.Context, conn *Connection, baseURL *url.URL, method, urlStr string, body map[string]string) error {
+	ctx, cancel := c.streamContext(ctx)
+	defer cancel()
+
+	// Reconnect state is local, so connections sharing a Client do
+	// not interfere with each other.
+	var (
+		kind     backoffKind
+		attempt  int
+		wait     time.Duration
+		resigned bool
+		reauthed bool
+	)
+	for {
+		// Failing to authenticate, such as when a token exchange
+		// fails, is retried like a failed connection.
+		var resp *http.Response
+		req, err := c.newRequest(ctx, baseURL, method, urlStr, body)
+		if err == nil {
+			resp, err = c.Do(req)
+		}
+		if err == nil {
+			conn.logger.Info("twitterstream: connected", "url", req.URL.Redacted(), "attempt", attempt)
+
+			var n int
+			n, err = c.dispatchResponse(ctx, conn, resp)
+			if n > 0 {
+				// The stream was delivering messages, so the next
+				// failure starts a fresh schedule.
+				attempt, wait, resigned, reauthed = 0, 0, false, false
+			}
+		}
+		if c.isClosed() {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// A 401 caused by a skewed local clock is retried
+		// immediately, once, signed with the corrected time.
+		if !resigned && c.adjustClock(conn, err) {
+			resigned = true
+			continue
+		}
+
+		// Cached credentials rejected with 401 are dropped and the
+		// request retried once with fresh ones.
+		if inv, ok := c.auth.(Invalidator); ok && !reauthed && errors.Is(err, ErrUnauthorized) {
+			inv.Invalidate()
+			reauthed = true
+			continue
+		}
+
+		k := backoffFor(err)
+		if k == backoffNone || attempt >= c.maxReconnects() {
+			conn.logger.Error("twitterstream: stream ended", "attempt", attempt, "error", err)
+			return err
+		}
+		if k != kind {
+			kind = k
+			wait = 0
+		}
+		wait = nextBackoff(kind, wait)
+		attempt++
+		conn.reconnects.Add(1)
+
+		conn.logger.Warn("twitterstream: reconnecting", "attempt", attempt, "wait", wait, "error", err)
+		if c.config.OnReconnect != nil {
+			c.config.OnReconnect(conn, attempt, wait, err)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// maxReconnects returns the configured reconnect limit.
+func (c *Client) maxReconnects() int {
+	switch n := c.config.MaxReconnects; {
+	case n < 0:
+		return 0
+	case n == 0:
+		return MaxReconnects
+	default:
+		return n
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type NextBackoffTest struct {
+	kind backoffKind
+	want []time.Duration
+}
+
+var nextBackoffTests = []NextBackoffTest{
+	{
+		backoffTCP,
+		[]time.Duration{
+			250 * time.Millisecond,
+			500 * time.Millisecond,
+			750 * time.Millisecond,
+			time.Second,
+		},
+	},
+	{
+		backoffHTTP,
+		[]time.Duration{
+			5 * time.Second,
+			10 * time.Second,
+			20 * time.Second,
+			40 * time.Second,
+			80 * time.Second,
+			160 * time.Second,
+			320 * time.Second,
+			320 * time.Second,
+		},
+	},
+	{
+		backoffRateLimit,
+		[]time.Duration{
+			time.Minute,
+			2 * time.Minute,
+			4 * time.Minute,
+			8 * time.Minute,
+		},
+	},
+}
+
+func TestNextBackoff(t *testing.T) {
+	for _, tt := range nextBackoffTests {
+		var wait time.Duration
+		for i, want := range tt.want {
+			wait = nextBackoff(tt.kind, wait)
+			if wait != want {
+				t.Errorf("backoff %d of kind %d = %v, want %v", i+1, tt.kind, wait, want)
+			}
+		}
+	}
+
+	var wait time.Duration
+	for i := 0; i < 100; i++ {
+		wait = nextBackoff(backoffTCP, wait)
+	}
+	if wait != tcpBackoffMax {
+		t.Errorf("TCP backoff capped at %v, want %v", wait, tcpBackoffMax)
+	}
+}
+
+type BackoffForTest struct {
+	err  error
+	want backoffKind
+}
+
+func responseError(code int) error {
+	return &ErrorResponse{Response: &http.Response{StatusCode: code}}
+}
+
+var backoffForTests = []BackoffForTest{
+	{io.EOF, backoffTCP},
+	{errors.New("connection reset by peer"), backoffTCP},
+	{responseError(420), backoffRateLimit},
+	{responseError(http.StatusServiceUnavailable), backoffHTTP},
+	{responseError(http.StatusInternalServerError), backoffHTTP},
+	{responseError(http.StatusUnauthorized), backoffNone},
+	{responseError(http.StatusNotFound), backoffNone},
+	{responseError(http.StatusRequestEntityTooLarge), backoffNone},
+	{&DisconnectError{&Disconnect{Code: 1}}, backoffTCP},
+	{&DisconnectError{&Disconnect{Code: 12}}, backoffTCP},
+	{&DisconnectError{&Disconnect{Code: 6}}, backoffNone},
+	{&DisconnectError{&Disconnect{Code: 7}}, backoffNone},
+}
+
+func TestBackoffFor(t *testing.T) {
+	for _, tt := range backoffForTests {
+		if actual := backoffFor(tt.err); actual != tt.want {
+			t.Errorf("backoffFor(%v) = %d, want %d", tt.err, actual, tt.want)
+		}
+	}
+}
+
+func TestConnectReconnects(t *testing.T) {
+	var connects int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connects++
+		fmt.Fprint(w, "\r\n")
+	}))
+	defer ts.Close()
+
+	var attempts []int
+	client := NewClient(&Config{
+		BaseURL:       ts.URL + "/1.1/",
+		MaxReconnects: 2,
+		OnReconnect: func(conn *Connection, attempt int, wait time.Duration, err error) {
+			if conn == nil {
+				t.Error("OnReconnect conn = nil")
+			}
+			attempts = append(attempts, attempt)
+			if err != io.EOF {
+				t.Errorf("OnReconnect err = %v, want %v", err, io.EOF)
+			}
+		},
+	})
+
+	err := client.Public.Sample()
+	if err != io.EOF {
+		t.Errorf("Sample returned %v, want %v", err, io.EOF)
+	}
+	if connects != 3 {
+		t.Errorf("server saw %d connections, want 3", connects)
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("OnReconnect attempts = %v, want [1 2]", attempts)
+	}
+}
+
+func TestConnectDisconnectMessage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "{\"disconnect\":{\"code\":7,\"stream_name\":\"gedex-sample\",\"reason\":\"admin logout\"}}\r\n")
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	handled := make(chan *Disconnect, 1)
+	client.HandleFunc("disconnect", func(s *Stream) {
+		handled <- s.DisconnectNotice.Disconnect
+	})
+
+	err := client.Public.Sample()
+	de, ok := err.(*DisconnectError)
+	if !ok {
+		t.Fatalf("Sample returned %v, want *DisconnectError", err)
+	}
+	if de.Disconnect.Code != 7 {
+		t.Errorf("DisconnectError code = %d, want 7", de.Disconnect.Code)
+	}
+
+	select {
+	case d := <-handled:
+		if d.ReasonByCode() != DisconnectCode[7] {
+			t.Errorf("ReasonByCode() = %q, want %q", d.ReasonByCode(), DisconnectCode[7])
+		}
+	case <-time.After(time.Second):
+		t.Error("disconnect handler was not called")
+	}
+}
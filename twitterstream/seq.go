@@ -0,0 +1,81 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"iter"
+)
+
+// seq returns an iterator over the messages of the connection made
+// by open. Messages are pulled one at a time and are not passed to
+// handlers. The error ending the connection, if any, is yielded
+// last with a nil message. Stopping the iteration closes the
+// connection.
+func seq(open func(opts ...ConnOption) (*Connection, error)) iter.Seq2[*Stream, error] {
+	return func(yield func(*Stream, error) bool) {
+		conn, err := open(WithMessages(0), withoutHandlers())
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer conn.Close()
+
+		for s := range conn.Messages() {
+			if !yield(s, nil) {
+				return
+			}
+		}
+		if err := conn.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// SampleSeq returns an iterator over the messages of a sample
+// stream. The stream is opened when iteration starts and closed
+// when it stops or ctx is done.
+func (s *PublicStreams) SampleSeq(ctx context.Context) iter.Seq2[*Stream, error] {
+	return seq(func(opts ...ConnOption) (*Connection, error) {
+		return s.OpenSample(ctx, opts...)
+	})
+}
+
+// FilterSeq is like SampleSeq for a filter stream.
+func (s *PublicStreams) FilterSeq(ctx context.Context, f map[string]string) iter.Seq2[*Stream, error] {
+	return seq(func(opts ...ConnOption) (*Connection, error) {
+		return s.OpenFilter(ctx, f, opts...)
+	})
+}
+
+// FilterWithSeq is like SampleSeq for a filter stream with typed
+// parameters.
+func (s *PublicStreams) FilterWithSeq(ctx context.Context, p *FilterParams) iter.Seq2[*Stream, error] {
+	return seq(func(opts ...ConnOption) (*Connection, error) {
+		return s.OpenFilterWith(ctx, p, opts...)
+	})
+}
+
+// FirehoseSeq is like SampleSeq for a firehose stream.
+func (s *PublicStreams) FirehoseSeq(ctx context.Context) iter.Seq2[*Stream, error] {
+	return seq(func(opts ...ConnOption) (*Connection, error) {
+		return s.OpenFirehose(ctx, opts...)
+	})
+}
+
+// GetSeq is like PublicStreams.SampleSeq for a user stream.
+func (s *UserStreams) GetSeq(ctx context.Context, f map[string]string) iter.Seq2[*Stream, error] {
+	return seq(func(opts ...ConnOption) (*Connection, error) {
+		return s.Open(ctx, f, opts...)
+	})
+}
+
+// GetSeq is like PublicStreams.SampleSeq for a site stream.
+func (s *SiteStreams) GetSeq(ctx context.Context, f map[string]string) iter.Seq2[*Stream, error] {
+	return seq(func(opts ...ConnOption) (*Connection, error) {
+		return s.Open(ctx, f, opts...)
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSampleSeqBreak(t *testing.T) {
+	gone := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 1; i <= 10; i++ {
+			fmt.Fprintf(w, "{\"limit\":{\"track\":%d}}\r\n", i)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(gone)
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	var got []int64
+	for s, err := range client.Public.SampleSeq(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s.LimitNotice.Limit.Track)
+		if len(got) == 3 {
+			break
+		}
+	}
+	if fmt.Sprint(got) != "[1 2 3]" {
+		t.Errorf("SampleSeq yielded %v, want [1 2 3]", got)
+	}
+
+	select {
+	case <-gone:
+	case <-time.After(time.Second):
+		t.Error("connection not closed after break")
+	}
+}
+
+func TestSampleSeqError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	n := 0
+	for s, err := range client.Public.SampleSeq(context.Background()) {
+		n++
+		if s != nil {
+			t.Errorf("SampleSeq yielded message %s", s.Raw)
+		}
+		if _, ok := err.(*ErrorResponse); !ok {
+			t.Errorf("SampleSeq yielded error %v, want *ErrorResponse", err)
+		}
+	}
+	if n != 1 {
+		t.Errorf("SampleSeq yielded %d times, want 1", n)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newLimitServer returns a server that streams n limit notices and
+// then keeps the connection open until the client goes away.
+func newLimitServer(n int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(w, "{\"limit\":{\"track\":%d}}\r\n", i)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+}
+
+func TestShutdownDrainsHandlers(t *testing.T) {
+	ts := newLimitServer(10)
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	var received, handled atomic.Int32
+	first := make(chan struct{}, 1)
+	client.HandleFunc("limit", func(s *Stream) {
+		received.Add(1)
+		select {
+		case first <- struct{}{}:
+		default:
+		}
+		time.Sleep(20 * time.Millisecond)
+		handled.Add(1)
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- client.Public.Sample()
+	}()
+	<-first
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	dropped, err := client.Shutdown(ctx)
+	if err != nil || dropped != 0 {
+		t.Errorf("Shutdown() = %d, %v, want 0, nil", dropped, err)
+	}
+	if h, r := handled.Load(), received.Load(); h != r {
+		t.Errorf("Shutdown returned with %d of %d handlers finished", h, r)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Sample returned %v after Shutdown, want nil", err)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	ts := newLimitServer(3)
+	defer ts.Close()
+
+	client := NewClient(&Config{BaseURL: ts.URL + "/1.1/"})
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	client.HandleFunc("limit", func(s *Stream) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	})
+
+	go client.Public.Sample()
+	<-started
+
+	// Wait for the reader to queue the remaining messages behind the
+	// blocked handler.
+	deadline := time.Now().Add(5 * time.Second)
+	for client.dispatcher.pending.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d messages pending, want 3", client.dispatcher.pending.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	dropped, err := client.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Shutdown returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if dropped != 3 {
+		t.Errorf("Shutdown reported %d dropped messages, want 3", dropped)
+	}
+}
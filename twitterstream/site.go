@@ -6,17 +6,28 @@
 package twitterstream
 
 import (
+	"context"
 	"net/url"
 )
 
 type SiteStreams struct {
-	client *Client
+	client  *Client
+	baseURL *url.URL
 }
 
 func (s *SiteStreams) Get(f map[string]string) error {
-	baseURL, _ := url.Parse("https://sitestream.twitter.com/1.1/")
-	s.client.baseURL = baseURL
+	return s.GetContext(context.Background(), f)
+}
+
+// GetContext is like Get but stops the stream and returns ctx.Err()
+// as soon as ctx is done.
+func (s *SiteStreams) GetContext(ctx context.Context, f map[string]string) error {
+	return waitConn(s.Open(ctx, f))
+}
 
+// Open opens a site stream that runs until it is closed or ctx is
+// done.
+func (s *SiteStreams) Open(ctx context.Context, f map[string]string, opts ...ConnOption) (*Connection, error) {
 	u := "site.json"
 
 	params := []string{"follow", "with", "replies"}
@@ -28,15 +39,5 @@ func (s *SiteStreams) Get(f map[string]string) error {
 	}
 	body["stall_warnings"] = "true"
 
-	req, err := s.client.NewRequest("POST", u, body)
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return err
-	}
-
-	return s.client.DispatchResponse(resp)
+	return s.client.open(ctx, s.baseURL, "POST", u, body, opts)
 }
@@ -0,0 +1,70 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStallTimeout represents how long a stream may go without
+// receiving any bytes before it is considered stalled. Twitter sends
+// a keep-alive newline every 30 seconds, so three missed keep-alives
+// mean the connection is dead.
+const DefaultStallTimeout = 90 * time.Second
+
+// ErrStall is returned when a stream has not received any bytes
+// within the stall timeout.
+var ErrStall = errors.New("twitterstream: stream stalled")
+
+// idleReader wraps a stream body and closes it when no bytes have
+// been read for timeout, unblocking any pending Read.
+type idleReader struct {
+	rc      io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	stall   atomic.Bool
+}
+
+func newIdleReader(rc io.ReadCloser, timeout time.Duration) *idleReader {
+	r := &idleReader{rc: rc, timeout: timeout}
+	r.timer = time.AfterFunc(timeout, func() {
+		r.stall.Store(true)
+		r.rc.Close()
+	})
+	return r
+}
+
+// Read reads from the underlying body, resetting the idle timer
+// whenever bytes arrive.
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+// Close stops the idle timer and closes the underlying body.
+func (r *idleReader) Close() error {
+	r.timer.Stop()
+	return r.rc.Close()
+}
+
+// stalled reports whether the body was closed by the idle timer.
+func (r *idleReader) stalled() bool {
+	return r.stall.Load()
+}
+
+// stallTimeout returns the configured stall timeout.
+func (c *Client) stallTimeout() time.Duration {
+	if c.config.StallTimeout > 0 {
+		return c.config.StallTimeout
+	}
+	return DefaultStallTimeout
+}
@@ -0,0 +1,59 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDispatchResponseStall(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "\r\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	client := NewClient(&Config{
+		BaseURL:       ts.URL + "/1.1/",
+		MaxReconnects: -1,
+		StallTimeout:  100 * time.Millisecond,
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- client.Public.Sample()
+	}()
+
+	select {
+	case err := <-done:
+		if err != ErrStall {
+			t.Errorf("Sample returned %v, want %v", err, ErrStall)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sample did not return after stream stalled")
+	}
+}
+
+func TestWarningFallingBehind(t *testing.T) {
+	raw := `{"warning":{"code":"FALLING_BEHIND","message":"Your connection is falling behind and messages are being queued for delivery to you. Your queue is now over 60% full. You will be disconnected when the queue is full.","percent_full":60}}`
+
+	var n WarningNotice
+	if err := json.Unmarshal([]byte(raw), &n); err != nil {
+		t.Fatal(err)
+	}
+	if !n.Warning.FallingBehind() {
+		t.Errorf("FallingBehind() = false for code %q", n.Warning.Code)
+	}
+	if n.Warning.PercentFull != 60 {
+		t.Errorf("PercentFull = %v, want 60", n.Warning.PercentFull)
+	}
+}
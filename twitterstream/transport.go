@@ -0,0 +1,62 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Timeouts of the default stream transport. None of them bounds the
+// response body, which stays open for as long as the stream runs.
+const (
+	DefaultDialTimeout           = 30 * time.Second
+	DefaultTLSHandshakeTimeout   = 10 * time.Second
+	DefaultResponseHeaderTimeout = 30 * time.Second
+)
+
+// NewStreamTransport returns an http.Transport suited to long-lived
+// streams. Connecting, the TLS handshake and waiting for response
+// headers are bounded by the Default*Timeout constants, reading the
+// body is not. The proxy is taken from the environment.
+func NewStreamTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   DefaultDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   DefaultTLSHandshakeTimeout,
+		ResponseHeaderTimeout: DefaultResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          10,
+	}
+}
+
+// httpClient returns the HTTP client described by conf. HTTPClient
+// wins over Transport, which wins over a stream transport built
+// with Proxy, TLSConfig and DisableCompression.
+func (conf *Config) httpClient() *http.Client {
+	if conf.HTTPClient != nil {
+		return conf.HTTPClient
+	}
+	if conf.Transport != nil {
+		return &http.Client{Transport: conf.Transport}
+	}
+
+	t := NewStreamTransport()
+	if conf.Proxy != nil {
+		t.Proxy = conf.Proxy
+	}
+	if conf.TLSConfig != nil {
+		t.TLSClientConfig = conf.TLSConfig
+	}
+	t.DisableCompression = conf.DisableCompression
+	return &http.Client{Transport: t}
+}
@@ -0,0 +1,98 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type countingTransport struct {
+	n int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.n++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestConfigHTTPClient(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+
+	// The test server certificate is only trusted by clients
+	// configured for it.
+	configs := map[string]*Config{
+		"HTTPClient": {HTTPClient: ts.Client()},
+		"TLSConfig":  {TLSConfig: &tls.Config{RootCAs: roots}},
+	}
+	for name, conf := range configs {
+		conf.BaseURL = ts.URL + "/1.1/"
+		conf.MaxReconnects = -1
+		if err := NewClient(conf).Public.Sample(); err != io.EOF {
+			t.Errorf("Sample with %s returned %v, want %v", name, err, io.EOF)
+		}
+	}
+
+	if err := NewClient(&Config{BaseURL: ts.URL + "/1.1/", MaxReconnects: -1}).Public.Sample(); err == io.EOF {
+		t.Error("Sample trusted the test server without TLS configuration")
+	}
+}
+
+func TestConfigTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	transport := new(countingTransport)
+	client := NewClient(&Config{
+		BaseURL:       ts.URL + "/1.1/",
+		MaxReconnects: -1,
+		Transport:     transport,
+	})
+	client.Public.Sample()
+
+	if transport.n != 1 {
+		t.Errorf("transport sent %d requests, want 1", transport.n)
+	}
+}
+
+func TestConfigProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := NewClient(&Config{
+		BaseURL:       "http://stream.example.com/1.1/",
+		MaxReconnects: -1,
+		Proxy:         http.ProxyURL(proxyURL),
+	})
+	client.Public.Sample()
+
+	if want := "http://stream.example.com/1.1/statuses/sample.json?stall_warnings=true"; proxied != want {
+		t.Errorf("proxy received %q, want %q", proxied, want)
+	}
+}
+
+func TestNewStreamTransport(t *testing.T) {
+	tr := NewStreamTransport()
+	if tr.TLSHandshakeTimeout == 0 || tr.ResponseHeaderTimeout == 0 {
+		t.Errorf("NewStreamTransport() has no handshake or header timeout")
+	}
+	if c := (&Config{}).httpClient(); c.Timeout != 0 {
+		t.Errorf("default client Timeout = %v, want 0", c.Timeout)
+	}
+}
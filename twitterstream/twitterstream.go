@@ -10,15 +10,18 @@ TODO: put example usage here
 package twitterstream
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -28,9 +31,17 @@ const (
 	// DefaultBaseURL represents default Twitter Stream base URL
 	DefaultBaseURL = "https://stream.twitter.com/1.1/"
 
+	// DefaultUserStreamBaseURL represents default user stream base URL
+	DefaultUserStreamBaseURL = "https://userstream.twitter.com/1.1/"
+
+	// DefaultSiteStreamBaseURL represents default site stream base URL
+	DefaultSiteStreamBaseURL = "https://sitestream.twitter.com/1.1/"
+
 	// UserAgent represents default client User-Agent
 	DefaultUserAgent = "go-twitterstream/" + Version
 
+	// MaxReconnects represents default number of consecutive
+	// reconnect attempts before a stream gives up
 	MaxReconnects = 10
 )
 
@@ -39,12 +50,19 @@ type Client struct {
 	// HTTP client used to communicate with the stream
 	client *http.Client
 
-	// Base URL for stream requests.
+	// Base URL for requests made with NewRequest, which is the
+	// public stream base URL.
 	baseURL *url.URL
 
 	// Client's config
 	config *Config
 
+	// Logger from config, or one discarding everything
+	logger *slog.Logger
+
+	// Authenticates stream requests
+	auth Authenticator
+
 	// Streaming endpoints
 	Public *PublicStreams
 	User   *UserStreams
@@ -52,36 +70,63 @@ type Client struct {
 
 	streamHandleMux *ProcessStreamMux
 
-	// Set to true by Disonnect
+	// Set to true and done closed by Disconnect
+	mu     sync.Mutex
 	closed bool
+	done   chan struct{}
+
+	// Running dispatch loops
+	readers sync.WaitGroup
+
+	// Hands decoded messages to handlers
+	dispatcher *dispatcher
+
+	// Last Connection ID handed out
+	nextConnID atomic.Uint64
 
-	// Reconnection
-	reconnectCount   int
-	reconnectTimeout int
+	// Twitter's clock minus the local clock, in nanoseconds
+	clockOffset atomic.Int64
 }
 
 // NewClient returns a new Twitter Streaming client. It expects
 // conf with valid credentials.
 func NewClient(conf *Config) *Client {
-
-	_baseURL := DefaultBaseURL
-	if conf.BaseURL != "" {
-		_baseURL = conf.BaseURL
-	}
-	baseURL, _ := url.Parse(_baseURL)
+	baseURL := parseBaseURL(conf.BaseURL, DefaultBaseURL)
 
 	c := &Client{
 		config:          conf,
-		client:          http.DefaultClient,
+		logger:          conf.Logger,
+		auth:            conf.authenticator(),
+		client:          conf.httpClient(),
 		baseURL:         baseURL,
-		streamHandleMux: &ProcessStreamMux{m: make(map[string]muxEntry)},
+		streamHandleMux: newProcessStreamMux(),
+		done:            make(chan struct{}),
+	}
+	if c.logger == nil {
+		c.logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	c.dispatcher = newDispatcher(conf.Dispatch, c.handleStream)
+	c.Public = &PublicStreams{client: c, baseURL: baseURL}
+	c.User = &UserStreams{
+		client:  c,
+		baseURL: parseBaseURL(conf.UserStreamBaseURL, DefaultUserStreamBaseURL),
+	}
+	c.Site = &SiteStreams{
+		client:  c,
+		baseURL: parseBaseURL(conf.SiteStreamBaseURL, DefaultSiteStreamBaseURL),
 	}
-	c.Public = &PublicStreams{client: c}
-	c.User = &UserStreams{client: c}
-	c.Site = &SiteStreams{client: c}
 	return c
 }
 
+// parseBaseURL returns rawurl parsed, or def if rawurl is empty.
+func parseBaseURL(rawurl, def string) *url.URL {
+	if rawurl == "" {
+		rawurl = def
+	}
+	u, _ := url.Parse(rawurl)
+	return u
+}
+
 // RequestParams represents parameters used when requesting stream
 // to any stream endpoints.
 type RequestParams struct {
@@ -90,6 +135,9 @@ type RequestParams struct {
 	Query    map[string]string
 	Body     map[string]string
 	OAuth    map[string]string
+
+	// Time of the oauth_timestamp, the current time if zero
+	Time time.Time
 }
 
 // NewRequest cretes a strema request. A relative URL can be provided in urlStr,
@@ -97,15 +145,27 @@ type RequestParams struct {
 // Relative URLs should always be specified without a preceding slash. The value
 // of body is url encoded and included as the request body if specified.
 func (c *Client) NewRequest(method, urlStr string, body map[string]string) (*http.Request, error) {
+	return c.newRequest(context.Background(), c.baseURL, method, urlStr, body)
+}
+
+// newRequest is NewRequest with a context that controls the
+// lifetime of the request and its response body.
+func (c *Client) newRequest(ctx context.Context, baseURL *url.URL, method, urlStr string, body map[string]string) (*http.Request, error) {
+	urlStr, body, err := c.framed(urlStr, body)
+	if err != nil {
+		return nil, err
+	}
+
 	rel, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, err
 	}
 
-	u := c.baseURL.ResolveReference(rel)
+	u := baseURL.ResolveReference(rel)
 
 	params := new(RequestParams)
 	params.Method = method
+	params.Time = c.now()
 	params.Endpoint = u.Scheme + "://" + u.Host + u.Path
 
 	q := u.Query()
@@ -127,7 +187,7 @@ func (c *Client) NewRequest(method, urlStr string, body map[string]string) (*htt
 		reqBody = strings.Trim(reqBody, "&")
 	}
 
-	req, err := http.NewRequest(method, u.String(), strings.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), strings.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +201,12 @@ func (c *Client) NewRequest(method, urlStr string, body map[string]string) (*htt
 		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	}
 	req.Header.Add("User-Agent", ua)
-	req.Header.Add("Authorization", c.config.authorizationHeader(params))
+	if !c.config.DisableCompression {
+		req.Header.Add("Accept-Encoding", "deflate, gzip")
+	}
+	if err := c.auth.Authenticate(req, params); err != nil {
+		return nil, err
+	}
 
 	return req, nil
 }
@@ -149,7 +214,8 @@ func (c *Client) NewRequest(method, urlStr string, body map[string]string) (*htt
 // Do sends a stream request and returns the stream response. The stream
 // response consists of a series of newline-delimited messages, where
 // "newline" is considered to be \r\n (in hex, 0x0D 0x0A) and "message"
-// is a JSON encoded data structure or a blank line. The return values
+// is a JSON encoded data structure or a blank line. With FramingLength
+// each message is preceded by its length instead. The return values
 // should always be consumed by DispatchResponse.
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	resp, err := c.client.Do(req)
@@ -166,120 +232,166 @@ func (c *Client) Do(req *http.Request) (*http.Response, error) {
 }
 
 // DispatchResponse reads http.Response and dispatches the chunk
-// to ProcessStream until client is closed.
+// to ProcessStream until client is closed. If no bytes arrive within
+// the stall timeout the body is closed and ErrStall is returned. If
+// the stream ends after a disconnect message, a *DisconnectError
+// is returned.
 func (c *Client) DispatchResponse(r *http.Response) error {
-	reader := bufio.NewReader(r.Body)
-	for {
-		if c.closed {
-			r.Body.Close()
-		}
-
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			// TODO: Check if connection stale
-
-			// TODO: try reconnect if stall or connection error
+	conn, _ := c.newConnection(nil)
+	_, err := c.dispatchResponse(context.Background(), conn, r)
+	return err
+}
 
-			return err
+// dispatchResponse is DispatchResponse that stops reading as soon
+// as ctx is done, returning ctx.Err(). Messages are counted in the
+// stats of conn and handled with its handlers. It also returns the
+// number of messages dispatched before the stream ended.
+func (c *Client) dispatchResponse(ctx context.Context, conn *Connection, r *http.Response) (int, error) {
+	body := newIdleReader(r.Body, c.stallTimeout())
+	defer body.Close()
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return 0, nil
+	}
+	c.readers.Add(1)
+	c.mu.Unlock()
+	defer c.readers.Done()
+
+	ctx, cancel := c.streamContext(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() {
+		body.Close()
+	})
+	defer stop()
+
+	conn.connected.Store(time.Now().UnixNano())
+
+	var n int
+	var disconnect *Disconnect
+	var msg []byte
+	frames, err := c.frameReader(r, body)
+	for err == nil {
+		if msg, err = frames.next(); err != nil {
+			break
 		}
-		line = bytes.TrimSpace(line)
-		if len(line) == 0 {
-			continue
+
+		n++
+		conn.messages.Add(1)
+		conn.lastMessage.Store(time.Now().UnixNano())
+		if stream := c.streamSwitcher(conn, msg); stream != nil {
+			stream.conn = conn
+			if stream.DisconnectNotice != nil {
+				disconnect = stream.DisconnectNotice.Disconnect
+			}
+			conn.deliver(ctx, stream)
+			if !conn.noHandlers {
+				c.dispatcher.dispatch(ctx, stream)
+			}
 		}
+	}
 
-		go c.streamSwitcher(line)
+	switch {
+	case c.isClosed():
+		return n, nil
+	case ctx.Err() != nil:
+		return n, ctx.Err()
+	case disconnect != nil:
+		return n, &DisconnectError{Disconnect: disconnect}
+	case body.stalled():
+		return n, ErrStall
 	}
+	return n, err
 }
 
-// Disconnect closes the client from the stream.
+// Disconnect closes the client from the stream. Streams stop reading
+// immediately, handlers already running are left to finish. A
+// disconnected client cannot be connected again.
 func (c *Client) Disconnect() {
-	c.closed = true
-}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-// streamSwitcher unmarshall the raw into general container
-// which then decoded into more specific type if matches with
-// any defined stream type.
-func (c *Client) streamSwitcher(raw []byte) {
-	var v map[string]interface{}
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+}
 
-	if err := json.Unmarshal(raw, &v); err != nil {
-		log.Printf("twitterstream: error unmarshal stream: %v\n", err)
-		return
+// Shutdown disconnects the client and waits for the handlers of
+// messages already read from the stream to finish. If ctx is done
+// first, messages still queued are discarded and Shutdown returns
+// ctx.Err() along with the number of messages that were queued or
+// whose handlers had not finished.
+func (c *Client) Shutdown(ctx context.Context) (int, error) {
+	c.Disconnect()
+
+	done := make(chan struct{})
+	go func() {
+		c.readers.Wait()
+		c.dispatcher.close()
+		c.dispatcher.wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0, nil
+	case <-ctx.Done():
+		c.dispatcher.discard.Store(true)
+		return int(c.dispatcher.pending.Load()), ctx.Err()
 	}
+}
 
-	stream := &Stream{Raw: raw}
-
-	var container interface{}
-	if _, ok := v["control"]; ok {
-	} else if _, ok := v["warning"]; ok {
-		stream.Type = "warning"
-		container = new(WarningNotice)
-		stream.WarningNotice = container.(*WarningNotice)
-	} else if d, ok := v["delete"]; ok {
-		ds := d.(map[string]interface{})
-		if _, ok = ds["status"]; ok {
-			stream.Type = "delete"
-			container = new(TweetDeletionNotice)
-			stream.TweetDeletionNotice = container.(*TweetDeletionNotice)
-		}
-	} else if sg, ok := v["scrub_geo"]; ok {
-		sgc := sg.(map[string]interface{})
-		if _, ok = sgc["up_to_status_id"]; ok {
-			stream.Type = "scrub_geo"
-			container = new(LocationDeletionNotice)
-			stream.LocationDeletionNotice = container.(*LocationDeletionNotice)
-		}
-	} else if _, ok := v["limit"]; ok {
-		stream.Type = "limit"
-		container = new(LimitNotice)
-		stream.LimitNotice = container.(*LimitNotice)
-	} else if _, ok := v["direct_message"]; ok {
-		stream.Type = "direct_message"
-		container = new(DirectMessageNotice)
-		stream.DirectMessageNotice = container.(*DirectMessageNotice)
-	} else if _, ok := v["status_withheld"]; ok {
-		stream.Type = "status_withheld"
-		container = new(StatusWithheldNotice)
-		stream.StatusWithheldNotice = container.(*StatusWithheldNotice)
-	} else if _, ok := v["user_withheld"]; ok {
-		stream.Type = "user_withheld"
-		container = new(UserWithheldNotice)
-		stream.UserWithheldNotice = container.(*UserWithheldNotice)
-	} else if _, ok := v["event"]; ok {
-		stream.Type = "event"
-		container = new(Event)
-		stream.Event = container.(*Event)
-	} else if _, ok := v["friends"]; ok {
-		stream.Type = "friends"
-		container = new(FriendsLists)
-		stream.FriendsLists = container.(*FriendsLists)
-	} else if _, ok := v["text"]; ok {
-		if _, ok = v["user"]; ok {
-			stream.Type = "tweet"
-			container = new(Tweet)
-			stream.Tweet = container.(*Tweet)
+// isClosed returns true if the client has been disconnected.
+func (c *Client) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// streamContext returns a context derived from ctx that is also
+// cancelled when the client is disconnected.
+func (c *Client) streamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
 		}
-	} else if _, ok := v["for_user"]; ok {
-		stream.Type = "for_user"
-		container = new(ForUser)
-		stream.ForUser = container.(*ForUser)
-	}
+	}()
+	return ctx, cancel
+}
 
-	go c.handleStream(stream, container)
+// streamSwitcher decodes raw into the stream type it matches.
+// Messages of no known type get the "unknown" type. It returns nil
+// if raw cannot be decoded.
+func (c *Client) streamSwitcher(conn *Connection, raw []byte) *Stream {
+	stream, err := decodeStream(raw)
+	if err != nil {
+		conn.logger.Warn("twitterstream: cannot decode message", "error", err, "raw", string(raw))
+		return nil
+	}
+	if stream.Type == "" {
+		stream.Type = "unknown"
+	}
+	return stream
 }
 
 // ProcessStreamMux is stream multiplexer.
 // It matches each incoming stream against a list of registered
-// stream type and calls the handler for the pattern that matches
+// stream type and calls the handlers for the pattern that matches
 // the stream type.
 type ProcessStreamMux struct {
-	mu sync.RWMutex
-	m  map[string]muxEntry
+	mu         sync.RWMutex
+	m          map[string][]Handler
+	middleware []func(Handler) Handler
 }
 
-type muxEntry struct {
-	streamType string
-	h          Handler
+func newProcessStreamMux() *ProcessStreamMux {
+	return &ProcessStreamMux{m: make(map[string][]Handler)}
 }
 
 // Stream represents a twitter stream.
@@ -297,37 +409,47 @@ type Stream struct {
 	UserWithheldNotice     *UserWithheldNotice
 	Event                  *Event
 	StatusWithheldNotice   *StatusWithheldNotice
-}
+	DisconnectNotice       *DisconnectNotice
+	ControlNotice          *ControlNotice
+	TooManyFollow          *TooManyFollow
 
-var availableStreamTypes = map[string]bool{
-	"control":         true,
-	"warning":         true,
-	"scrub_geo":       true,
-	"tweet":           true,
-	"limit":           true,
-	"delete":          true,
-	"friends":         true,
-	"direct_message":  true,
-	"status_withheld": true,
-	"user_withheld":   true,
-	"for_user":        true,
+	// Connection the stream arrived on
+	conn *Connection
 }
 
-var defaultStreamHandlers = map[string]func(*Stream){
-	"tweet":   defaultTweetStreamHandler,
-	"friends": defaultFriendsStreamHandler,
+// eventName returns the name of the event carried by the stream,
+// either directly or inside a site stream message.
+func (s *Stream) eventName() string {
+	switch {
+	case s.Event != nil:
+		return s.Event.Event
+	case s.ForUser != nil && s.ForUser.Event != nil:
+		return s.ForUser.Event.Event
+	}
+	return ""
 }
 
-func defaultTweetStreamHandler(s *Stream) {
-	log.Printf("@%v: %v\n", s.Tweet.User.ScreenName, s.Tweet.Text)
-}
-func defaultFriendsStreamHandler(s *Stream) {
-	log.Printf("@%+v\n", s.FriendsLists.Friends)
+var availableStreamTypes = map[string]bool{
+	"control":          true,
+	"disconnect":       true,
+	"warning":          true,
+	"too_many_follows": true,
+	"scrub_geo":        true,
+	"tweet":            true,
+	"limit":            true,
+	"delete":           true,
+	"friends":          true,
+	"direct_message":   true,
+	"status_withheld":  true,
+	"user_withheld":    true,
+	"event":            true,
+	"for_user":         true,
+	"unknown":          true,
 }
 
 // handle registers the stream handler for the given stream type.
-// If a handler already exists for targetted stream type, handle
-// panics.
+// Handlers registered for the same type are called in registration
+// order.
 func (mux *ProcessStreamMux) handle(streamType string, handler Handler) {
 	mux.mu.Lock()
 	defer mux.mu.Unlock()
@@ -335,27 +457,37 @@ func (mux *ProcessStreamMux) handle(streamType string, handler Handler) {
 	if handler == nil {
 		panic("twitterstream: nil handler")
 	}
-	if _, exists := mux.m[streamType]; exists {
-		panic(fmt.Sprintf("twitterstream: multiple registrations for %v", streamType))
-	}
+	mux.m[streamType] = append(mux.m[streamType], handler)
+}
 
-	mux.m[streamType] = muxEntry{h: handler, streamType: streamType}
+// registered returns the handlers registered for the given stream
+// type.
+func (mux *ProcessStreamMux) registered(streamType string) []Handler {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	return mux.m[streamType]
+}
+
+// Use appends middleware wrapping every dispatch of the mux,
+// whether or not a handler is registered for the stream. The first
+// middleware added is the outermost.
+func (mux *ProcessStreamMux) Use(middleware ...func(Handler) Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	mux.middleware = append(mux.middleware, middleware...)
 }
 
-// handler returns the handler to use for the given stream type.
-// If no registered handler found, it checks for default stream
-// handler. Otherwise nil is returned.
-func (mux *ProcessStreamMux) handler(streamType string) Handler {
+// wrap returns h wrapped in the middleware of the mux.
+func (mux *ProcessStreamMux) wrap(h Handler) Handler {
 	mux.mu.RLock()
 	defer mux.mu.RUnlock()
 
-	if h, exists := mux.m[streamType]; exists {
-		return h.h
-	}
-	if h, exists := defaultStreamHandlers[streamType]; exists {
-		return handlerFunc(h)
+	for i := len(mux.middleware) - 1; i >= 0; i-- {
+		h = mux.middleware[i](h)
 	}
-	return nil
+	return h
 }
 
 // Objects implementing the Handler interface can be
@@ -364,39 +496,163 @@ type Handler interface {
 	ProcessStream(*Stream)
 }
 
-// The handlerFunc type is an adapter to allow the use of
+// The HandlerFunc type is an adapter to allow the use of
 // odinary functions as stream handlers. If f is a function
-// with the appropriate signature, handlerFunc(f) is a
+// with the appropriate signature, HandlerFunc(f) is a
 // Handler object that calls f.
-type handlerFunc func(*Stream)
+type HandlerFunc func(*Stream)
 
 // ProcessStream calls f(stream)
-func (f handlerFunc) ProcessStream(stream *Stream) {
+func (f HandlerFunc) ProcessStream(stream *Stream) {
 	f(stream)
 }
 
-// handleStream handles the stream.
-func (c *Client) handleStream(stream *Stream, container interface{}) {
-	h := c.streamHandleMux.handler(stream.Type)
-	if h == nil {
-		log.Printf("twitterstream: No handler for %v stream", stream.Type)
-	} else {
-		if container != nil {
-			err := json.Unmarshal(stream.Raw, container)
-			if err != nil {
-				log.Printf("twitterstream: Error unmarshall: %v", err)
-			} else {
-				h.ProcessStream(stream)
+// ErrorHandler is a Handler that reports failure. When registered,
+// ProcessStreamErr is called instead of ProcessStream and its error
+// is passed to Config.OnHandlerError.
+type ErrorHandler interface {
+	Handler
+	ProcessStreamErr(*Stream) error
+}
+
+// The ErrorHandlerFunc type is an adapter to allow the use of
+// ordinary functions returning an error as stream handlers.
+type ErrorHandlerFunc func(*Stream) error
+
+// ProcessStream calls f(stream) and drops its error.
+func (f ErrorHandlerFunc) ProcessStream(stream *Stream) {
+	f(stream)
+}
+
+// ProcessStreamErr calls f(stream).
+func (f ErrorHandlerFunc) ProcessStreamErr(stream *Stream) error {
+	return f(stream)
+}
+
+// handleStream handles the stream through the middleware of the
+// client. A panicking middleware is reported like a panicking
+// handler.
+func (c *Client) handleStream(stream *Stream) {
+	defer c.recoverHandler(stream)
+	c.streamHandleMux.wrap(HandlerFunc(c.processStream)).ProcessStream(stream)
+}
+
+// processStream calls the handlers of the stream in registration
+// order, then the catch-all handlers.
+func (c *Client) processStream(stream *Stream) {
+	var connAll []Handler
+	if conn := stream.conn; conn != nil && conn.mux != nil {
+		connAll = conn.mux.registered("*")
+	}
+	hs := slices.Concat(c.handlers(stream), connAll, c.streamHandleMux.registered("*"))
+	if len(hs) == 0 {
+		c.streamLogger(stream).Debug("twitterstream: no handler for stream", "stream_type", stream.Type)
+		return
+	}
+	for _, h := range hs {
+		c.callHandler(h, stream)
+	}
+}
+
+// callHandler calls h with the stream, reporting its error or
+// panic. A failing handler does not keep the next ones from
+// running.
+func (c *Client) callHandler(h Handler, stream *Stream) {
+	defer c.recoverHandler(stream)
+
+	if eh, ok := h.(ErrorHandler); ok {
+		if err := eh.ProcessStreamErr(stream); err != nil {
+			c.handlerError(stream, err)
+		}
+		return
+	}
+	h.ProcessStream(stream)
+}
+
+// recoverHandler reports a panic of a handler running for stream.
+// It must be deferred.
+func (c *Client) recoverHandler(stream *Stream) {
+	if v := recover(); v != nil {
+		c.handlerError(stream, &PanicError{Value: v, Stack: debug.Stack()})
+	}
+}
+
+// handlerError reports err returned by a handler of stream.
+func (c *Client) handlerError(stream *Stream, err error) {
+	if c.config.OnHandlerError != nil {
+		c.config.OnHandlerError(stream.Type, stream.Raw, err)
+		return
+	}
+	c.streamLogger(stream).Error("twitterstream: stream handler failed", "stream_type", stream.Type, "error", err)
+}
+
+// streamLogger returns the logger of the connection stream arrived
+// on.
+func (c *Client) streamLogger(stream *Stream) *slog.Logger {
+	if stream.conn != nil {
+		return stream.conn.logger
+	}
+	return c.logger
+}
+
+// handlers returns the handlers of the stream. Events go to the
+// handlers registered for their event name if there are any.
+// Handlers of the connection the stream arrived on win over those
+// of the client.
+func (c *Client) handlers(stream *Stream) []Handler {
+	name := stream.eventName()
+	if conn := stream.conn; conn != nil && conn.mux != nil {
+		if name != "" {
+			if hs := conn.mux.registered(name); len(hs) > 0 {
+				return hs
 			}
 		}
+		if hs := conn.mux.registered(stream.Type); len(hs) > 0 {
+			return hs
+		}
+	}
+	if name != "" {
+		if hs := c.streamHandleMux.registered(name); len(hs) > 0 {
+			return hs
+		}
 	}
+	return c.streamHandleMux.registered(stream.Type)
 }
 
 // HandleFunc registers the stream handler function for the given stream type.
+// streamType can also be an event name such as "favorite" or "follow", in
+// which case handler receives those events instead of the "event" handler
+// (or the "for_user" handler on site streams). Several handlers can be
+// registered for one type, they are called in registration order.
+// Handlers for "*" receive every message after the handlers for its
+// type, see also HandleUnknown.
 func (c *Client) HandleFunc(streamType string, handler func(*Stream)) {
 	valid := isValidStreamType(streamType)
 	if !valid {
 		panic("twitterstream: unknown stream type " + streamType)
 	}
-	c.streamHandleMux.handle(streamType, handlerFunc(handler))
+	c.streamHandleMux.handle(streamType, HandlerFunc(handler))
+}
+
+// HandleUnknown registers handler for messages of no known type,
+// which have the "unknown" type and their payload in Raw. New kinds
+// of messages are passed to it rather than dropped.
+func (c *Client) HandleUnknown(handler func(*Stream)) {
+	c.streamHandleMux.handle("unknown", HandlerFunc(handler))
+}
+
+// HandleErrorFunc is like HandleFunc for a handler that returns an
+// error, which is passed to Config.OnHandlerError.
+func (c *Client) HandleErrorFunc(streamType string, handler func(*Stream) error) {
+	valid := isValidStreamType(streamType)
+	if !valid {
+		panic("twitterstream: unknown stream type " + streamType)
+	}
+	c.streamHandleMux.handle(streamType, ErrorHandlerFunc(handler))
+}
+
+// Use appends middleware wrapping every message dispatched to the
+// handlers of the client, see ProcessStreamMux.Use.
+func (c *Client) Use(middleware ...func(Handler) Handler) {
+	c.streamHandleMux.Use(middleware...)
 }
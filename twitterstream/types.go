@@ -5,6 +5,10 @@
 
 package twitterstream
 
+import (
+	"encoding/json"
+)
+
 type Tweet struct {
 	Contributors         *TweetContributors  `json:"contributors,omitempty"`
 	Coordinates          *TweetCoordinate    `json:"coordinates,omitempty"`
@@ -260,6 +264,23 @@ type Warning struct {
 	Code        string  `json:"code,omitempty"`
 	Message     string  `json:"message,omitempty"`
 	PercentFull float64 `json:"percent_full,omitempty"`
+	UserID      int64   `json:"user_id,omitempty"`
+}
+
+// WarningFallingBehind is the warning code sent when the client is
+// reading too slowly and its queue on the server is filling up.
+const WarningFallingBehind = "FALLING_BEHIND"
+
+// WarningFollowsOverLimit is the warning code sent when a user
+// stream follows more users than it is allowed to. Such warnings
+// are dispatched as too_many_follows streams.
+const WarningFollowsOverLimit = "FOLLOWS_OVER_LIMIT"
+
+// FallingBehind returns true if w warns that the client is reading
+// too slowly. PercentFull tells how close the server is to
+// disconnecting the client.
+func (w *Warning) FallingBehind() bool {
+	return w.Code == WarningFallingBehind
 }
 
 type DisconnectNotice struct {
@@ -294,12 +315,111 @@ var DisconnectCode = map[int]string{
 	12: "Shed load: The host the stream was connected to became overloaded and streams were disconnected to balance load. Reconnect as usual.",
 }
 
+// Event represents a user or site stream event. Event holds the
+// event name, such as "favorite" or "list_member_added", and
+// TargetObject holds a *Tweet for tweet events, a *List for list
+// events and nil otherwise.
 type Event struct {
-	Target       *User                  `json:"target,omitempty"`
-	Source       *User                  `json:"source,omitempty"`
-	Event        string                 `json:"event,omitempty"`
-	TargetObject map[string]interface{} `json:"target_object,omitempty"`
-	CreatedAt    string                 `json:"created_at,omitempty"`
+	Target       *User       `json:"target,omitempty"`
+	Source       *User       `json:"source,omitempty"`
+	Event        string      `json:"event,omitempty"`
+	TargetObject interface{} `json:"target_object,omitempty"`
+	CreatedAt    string      `json:"created_at,omitempty"`
+}
+
+// availableEventTypes maps each event name to the type of its
+// target object.
+var availableEventTypes = map[string]string{
+	"access_revoked":         "",
+	"block":                  "",
+	"unblock":                "",
+	"favorite":               "tweet",
+	"unfavorite":             "tweet",
+	"follow":                 "",
+	"unfollow":               "",
+	"list_created":           "list",
+	"list_destroyed":         "list",
+	"list_updated":           "list",
+	"list_member_added":      "list",
+	"list_member_removed":    "list",
+	"list_user_subscribed":   "list",
+	"list_user_unsubscribed": "list",
+	"quoted_tweet":           "tweet",
+	"user_update":            "",
+	"favorited_retweet":      "tweet",
+	"retweeted_retweet":      "tweet",
+	"mute":                   "",
+	"unmute":                 "",
+}
+
+// UnmarshalJSON decodes an event, decoding its target object
+// according to the event name.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Target       *User           `json:"target,omitempty"`
+		Source       *User           `json:"source,omitempty"`
+		Event        string          `json:"event,omitempty"`
+		TargetObject json.RawMessage `json:"target_object,omitempty"`
+		CreatedAt    string          `json:"created_at,omitempty"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	e.Target = v.Target
+	e.Source = v.Source
+	e.Event = v.Event
+	e.CreatedAt = v.CreatedAt
+	e.TargetObject = nil
+
+	if len(v.TargetObject) == 0 || string(v.TargetObject) == "null" {
+		return nil
+	}
+	switch availableEventTypes[v.Event] {
+	case "tweet":
+		t := new(Tweet)
+		if err := json.Unmarshal(v.TargetObject, t); err != nil {
+			return err
+		}
+		e.TargetObject = t
+	case "list":
+		l := new(List)
+		if err := json.Unmarshal(v.TargetObject, l); err != nil {
+			return err
+		}
+		e.TargetObject = l
+	}
+	return nil
+}
+
+// TargetTweet returns the tweet targeted by the event, or nil if
+// the event does not target a tweet.
+func (e *Event) TargetTweet() *Tweet {
+	t, _ := e.TargetObject.(*Tweet)
+	return t
+}
+
+// TargetList returns the list targeted by the event, or nil if the
+// event does not target a list.
+func (e *Event) TargetList() *List {
+	l, _ := e.TargetObject.(*List)
+	return l
+}
+
+type List struct {
+	CreatedAt       string `json:"created_at,omitempty"`
+	Description     string `json:"description,omitempty"`
+	Following       bool   `json:"following,omitempty"`
+	FullName        string `json:"full_name,omitempty"`
+	ID              int64  `json:"id,omitempty"`
+	IDStr           string `json:"id_str,omitempty"`
+	MemberCount     int    `json:"member_count,omitempty"`
+	Mode            string `json:"mode,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Slug            string `json:"slug,omitempty"`
+	SubscriberCount int    `json:"subscriber_count,omitempty"`
+	URI             string `json:"uri,omitempty"`
+	User            *User  `json:"user,omitempty"`
 }
 
 type FriendsLists struct {
@@ -312,9 +432,54 @@ type TooManyFollow struct {
 	UserID  int64  `json:"user_id,omitempty"`
 }
 
+// ForUser represents a site stream message sent on behalf of the
+// user ForUser. Message is set for friends lists and Event for
+// events, other messages are left undecoded.
 type ForUser struct {
 	ForUser string        `json:"for_user,omitempty"`
 	Message *FriendsLists `json:"message,omitempty"`
+	Event   *Event        `json:"-"`
+}
+
+// UnmarshalJSON decodes a site stream envelope. for_user may be
+// sent either as a number or as a string, it is left empty when
+// null.
+func (f *ForUser) UnmarshalJSON(data []byte) error {
+	var v struct {
+		ForUser json.RawMessage `json:"for_user,omitempty"`
+		Message json.RawMessage `json:"message,omitempty"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	f.ForUser, f.Message, f.Event = "", nil, nil
+	if len(v.ForUser) > 0 && v.ForUser[0] == '"' {
+		if err := json.Unmarshal(v.ForUser, &f.ForUser); err != nil {
+			return err
+		}
+	} else if len(v.ForUser) > 0 {
+		var id json.Number
+		if err := json.Unmarshal(v.ForUser, &id); err != nil {
+			return err
+		}
+		f.ForUser = id.String()
+	}
+
+	keys, err := scanKeys(v.Message)
+	if err != nil {
+		// Not an object, nothing to decode.
+		return nil
+	}
+	switch {
+	case keys&keyEvent != 0:
+		f.Event = new(Event)
+		return json.Unmarshal(v.Message, f.Event)
+	case keys&keyFriends != 0:
+		f.Message = new(FriendsLists)
+		return json.Unmarshal(v.Message, f.Message)
+	}
+	return nil
 }
 
 type ControlNotice struct {
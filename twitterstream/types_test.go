@@ -0,0 +1,111 @@
+// Copyright 2013 The go-twitterstream AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package twitterstream
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type EventTest struct {
+	in    string
+	tweet int64
+	list  int64
+}
+
+var eventTests = []EventTest{
+	{
+		`{"event":"favorite","created_at":"Sat Sep 04 16:10:54 +0000 2010","target":{"id":1},"source":{"id":2},"target_object":{"id":13,"text":"hello","user":{"id":1}}}`,
+		13,
+		0,
+	},
+	{
+		`{"event":"list_member_added","target":{"id":1},"source":{"id":2},"target_object":{"id":77,"slug":"gophers","mode":"public"}}`,
+		0,
+		77,
+	},
+	{
+		`{"event":"follow","target":{"id":1},"source":{"id":2}}`,
+		0,
+		0,
+	},
+	{
+		`{"event":"access_revoked","target":{"id":1},"source":{"id":2},"target_object":{"token":"abc","consumer_key":"xyz"}}`,
+		0,
+		0,
+	},
+}
+
+func TestEventTargetObject(t *testing.T) {
+	for _, tt := range eventTests {
+		var e Event
+		if err := json.Unmarshal([]byte(tt.in), &e); err != nil {
+			t.Errorf("Unmarshal(%s) returned error %v", tt.in, err)
+			continue
+		}
+		if e.Source == nil || e.Source.ID != 2 {
+			t.Errorf("Unmarshal(%s) Source = %+v, want ID 2", tt.in, e.Source)
+		}
+
+		var tweet, list int64
+		if tw := e.TargetTweet(); tw != nil {
+			tweet = tw.ID
+		}
+		if l := e.TargetList(); l != nil {
+			list = l.ID
+		}
+		if tweet != tt.tweet || list != tt.list {
+			t.Errorf("Unmarshal(%s) target tweet, list = %d, %d, want %d, %d", tt.in, tweet, list, tt.tweet, tt.list)
+		}
+		if tt.tweet == 0 && tt.list == 0 && e.TargetObject != nil {
+			t.Errorf("Unmarshal(%s) TargetObject = %#v, want nil", tt.in, e.TargetObject)
+		}
+	}
+}
+
+func TestForUserEvent(t *testing.T) {
+	in := `{"for_user":1888,"message":{"event":"unfavorite","source":{"id":1888},"target_object":{"id":99,"text":"hi","user":{"id":3}}}}`
+
+	stream, err := decodeStream([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.Type != "for_user" || stream.ForUser.ForUser != "1888" {
+		t.Errorf("decodeStream(%s) = type %q for user %q, want for_user 1888", in, stream.Type, stream.ForUser.ForUser)
+	}
+	if stream.eventName() != "unfavorite" {
+		t.Errorf("eventName() = %q, want unfavorite", stream.eventName())
+	}
+	if tw := stream.ForUser.Event.TargetTweet(); tw == nil || tw.ID != 99 {
+		t.Errorf("TargetTweet() = %+v, want tweet 99", tw)
+	}
+}
+
+type ForUserTest struct {
+	in      string
+	forUser string
+}
+
+var forUserTests = []ForUserTest{
+	{`{"for_user":1888,"message":{}}`, "1888"},
+	{`{"for_user":"1888","message":{}}`, "1888"},
+	{`{"for_user":"a\"b\u00e9","message":{}}`, "a\"bé"},
+	{`{"for_user":null,"message":{}}`, ""},
+	{`{"message":{}}`, ""},
+}
+
+func TestForUserID(t *testing.T) {
+	for _, tt := range forUserTests {
+		f := ForUser{ForUser: "stale"}
+		if err := json.Unmarshal([]byte(tt.in), &f); err != nil {
+			t.Errorf("Unmarshal(%s) returned error %v", tt.in, err)
+			continue
+		}
+		if f.ForUser != tt.forUser {
+			t.Errorf("Unmarshal(%s) ForUser = %q, want %q", tt.in, f.ForUser, tt.forUser)
+		}
+	}
+}
@@ -6,17 +6,28 @@
 package twitterstream
 
 import (
+	"context"
 	"net/url"
 )
 
 type UserStreams struct {
-	client *Client
+	client  *Client
+	baseURL *url.URL
 }
 
 func (s *UserStreams) Get(f map[string]string) error {
-	baseURL, _ := url.Parse("https://userstream.twitter.com/1.1/")
-	s.client.baseURL = baseURL
+	return s.GetContext(context.Background(), f)
+}
+
+// GetContext is like Get but stops the stream and returns ctx.Err()
+// as soon as ctx is done.
+func (s *UserStreams) GetContext(ctx context.Context, f map[string]string) error {
+	return waitConn(s.Open(ctx, f))
+}
 
+// Open opens a user stream that runs until it is closed or ctx is
+// done.
+func (s *UserStreams) Open(ctx context.Context, f map[string]string, opts ...ConnOption) (*Connection, error) {
 	u := "user.json"
 
 	params := url.Values{
@@ -36,15 +47,5 @@ func (s *UserStreams) Get(f map[string]string) error {
 	}
 	u += "?" + params.Encode()
 
-	req, err := s.client.NewRequest("GET", u, nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return err
-	}
-
-	return s.client.DispatchResponse(resp)
+	return s.client.open(ctx, s.baseURL, "GET", u, nil, opts)
 }
@@ -8,14 +8,20 @@ package twitterstream
 import (
 	"bytes"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha1"
 	"encoding/base64"
 	"fmt"
-	"math/rand"
 	"sort"
 	"strings"
 )
 
+// Escape percent-encodes s as OAuth 1.0a requires of the keys and
+// values in RequestParams.
+func Escape(s string) string {
+	return escape(s)
+}
+
 // escape escapes the string according to the RFC3986.
 // Copied from escape func from net/url pkg but with little
 // adjustment to count space as hexCount and mode always
@@ -67,8 +73,16 @@ func shouldEscape(c byte) bool {
 }
 
 // isValidStreamType returns true if the specified streamType is a valid
-// streamType. See availableStreamTypes for defined stream types.
+// streamType. See availableStreamTypes for defined stream types and
+// availableEventTypes for defined event names.
 func isValidStreamType(streamType string) bool {
+	if streamType == "*" {
+		return true
+	}
+	if _, exists := availableEventTypes[streamType]; exists {
+		return true
+	}
+
 	v, exists := availableStreamTypes[streamType]
 	if !v || !exists {
 		return false
@@ -77,12 +91,24 @@ func isValidStreamType(streamType string) bool {
 	return true
 }
 
-// Nonce returns a random string with length n.
+// Nonce returns a random alphanumeric string with length n, read
+// from crypto/rand.
 func Nonce(n int) string {
-	var alphanum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	buf := make([]byte, n)
-	for i := 0; i < n; i++ {
-		buf[i] = alphanum[rand.Intn(len(alphanum))]
+	const alphanum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	// Bytes from 248 up are skipped so that every character is
+	// equally likely.
+	const limit = 256 - 256%len(alphanum)
+
+	buf := make([]byte, 0, n)
+	random := make([]byte, n+n/4+1)
+	for len(buf) < n {
+		rand.Read(random)
+		for _, b := range random {
+			if int(b) < limit && len(buf) < n {
+				buf = append(buf, alphanum[int(b)%len(alphanum)])
+			}
+		}
 	}
 	return string(buf)
 }
@@ -6,6 +6,7 @@
 package twitterstream
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -62,10 +63,18 @@ var validStreamTypeTests = []ValidStreamTypeTest{
 		"control",
 		true,
 	},
+	{
+		"disconnect",
+		true,
+	},
 	{
 		"warning",
 		true,
 	},
+	{
+		"too_many_follows",
+		true,
+	},
 	{
 		"scrub_geo",
 		true,
@@ -102,6 +111,26 @@ var validStreamTypeTests = []ValidStreamTypeTest{
 		"for_user",
 		true,
 	},
+	{
+		"event",
+		true,
+	},
+	{
+		"favorite",
+		true,
+	},
+	{
+		"list_member_added",
+		true,
+	},
+	{
+		"unknown",
+		true,
+	},
+	{
+		"*",
+		true,
+	},
 	{
 		"invalid",
 		false,
@@ -112,7 +141,7 @@ func Test_isValidStreamType(t *testing.T) {
 	for _, tt := range validStreamTypeTests {
 		actual := isValidStreamType(tt.in)
 		if tt.out != actual {
-			t.Errorf("isValidStreamType(%q) = %q, want %q", tt.in, actual, tt.out)
+			t.Errorf("isValidStreamType(%q) = %v, want %v", tt.in, actual, tt.out)
 		}
 	}
 }
@@ -128,6 +157,10 @@ func TestNonce(t *testing.T) {
 		if len(nonce) != 42-i {
 			t.Errorf("Nonce(%d) generate string with length %d, want %d", 42-i, len(nonce), 42-i)
 		}
+		if strings.Trim(nonce, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") != "" {
+			t.Errorf("Nonce(%d) = %q, want only alphanumeric characters", 42-i, nonce)
+		}
+		prev = nonce
 	}
 }
 